@@ -24,10 +24,16 @@ func New() *Book {
 }
 
 // Entry is a weighted move in an internally loaded opening book.
+// WhiteWins, Draws and BlackWins count the results of the games the move was
+// played in. Only books made with FromPGN have them since polyglot files do
+// not store results.
 type Entry struct {
-	Move   move.Move
-	Weight uint16
-	Learn  uint32
+	Move      move.Move
+	Weight    uint16
+	Learn     uint32
+	WhiteWins uint32
+	Draws     uint32
+	BlackWins uint32
 }
 
 // PolyglotEntry is a line in a polyglot opening book.
@@ -177,8 +183,8 @@ func decodeMove(m uint16) move.Move {
 
 	promo := bits(m, 4)
 	var promoStr string
-	if promo < 4 {
-		promoStr = []string{"", "k", "b", "r", "q"}[promo]
+	if promo <= 4 {
+		promoStr = []string{"", "n", "b", "r", "q"}[promo]
 	}
 	mv := from.String() + to.String() + promoStr
 	switch mv {
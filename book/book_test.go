@@ -2,6 +2,7 @@ package book
 
 import (
 	"fmt"
+	"github.com/reecer/chess/position/move"
 	"os"
 	"testing"
 )
@@ -35,6 +36,15 @@ func TestBitsMasks(t *testing.T) {
 	}
 }
 
+func TestDecodePromotion(t *testing.T) {
+	// e7e8 with the promotion piece in bits 12-14: 1 is a knight and 4 a queen.
+	for m, expected := range map[uint16]string{1<<12 | 6<<9 | 4<<6 | 7<<3 | 4: "e7e8n", 4<<12 | 6<<9 | 4<<6 | 7<<3 | 4: "e7e8q"} {
+		if mv := decodeMove(m); mv != move.Parse(expected) {
+			t.Error("wanted", expected, "but got", mv)
+		}
+	}
+}
+
 func TestOpenSaveOpen(t *testing.T) {
 	if os.Getenv("TEST_BOOK") == "" {
 		t.SkipNow()
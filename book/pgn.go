@@ -5,7 +5,9 @@ import (
 	"github.com/reecer/chess/game"
 	"github.com/reecer/chess/pgn"
 	"github.com/reecer/chess/polyglot"
+	"github.com/reecer/chess/position"
 	"github.com/reecer/chess/position/move"
+	"sort"
 )
 
 // FromPGN creates an opening book from a PGN. 'depth' is the number of plies
@@ -36,7 +38,7 @@ func (b *Book) addPgn(pgn *pgn.PGN, depth int) {
 	for d, m := range pgn.Moves {
 		if d >= depth {
 			for _, s := range staged {
-				b.addMove(s.key, s.move)
+				b.addMove(s.key, s.move, pgn.Tags["Result"])
 			}
 			return
 		}
@@ -53,14 +55,52 @@ func (b *Book) addPgn(pgn *pgn.PGN, depth int) {
 	}
 }
 
-func (b *Book) addMove(key uint64, m move.Move) {
+func (b *Book) addMove(key uint64, m move.Move, result string) {
 	ml := b.Positions[key]
-	for i := range ml {
-		if ml[i].Move == m {
-			ml[i].Weight++
-			b.Positions[key] = ml
-			return
+	i := 0
+	for i < len(ml) && ml[i].Move != m {
+		i++
+	}
+	if i == len(ml) {
+		ml = append(ml, Entry{Move: m})
+	}
+	ml[i].Weight++
+	switch result {
+	case "1-0":
+		ml[i].WhiteWins++
+	case "1/2-1/2":
+		ml[i].Draws++
+	case "0-1":
+		ml[i].BlackWins++
+	}
+	b.Positions[key] = ml
+}
+
+// MoveStat is how often a book move was played and how the games went. The
+// percentages are of the games with a known result.
+type MoveStat struct {
+	Move      move.Move
+	Count     int
+	WhiteWins float64
+	Draws     float64
+	BlackWins float64
+}
+
+// Stats returns the book moves for the position along with their results,
+// most played first. The results are only known if the book was made with
+// FromPGN.
+func (b *Book) Stats(p *position.Position) []MoveStat {
+	entries := append([]Entry(nil), b.Positions[polyglot.Encode(p)]...)
+	sort.Sort(byWeight(entries))
+	var stats []MoveStat
+	for _, e := range entries {
+		s := MoveStat{Move: e.Move, Count: int(e.Weight)}
+		if games := float64(e.WhiteWins + e.Draws + e.BlackWins); games > 0 {
+			s.WhiteWins = float64(e.WhiteWins) / games * 100
+			s.Draws = float64(e.Draws) / games * 100
+			s.BlackWins = float64(e.BlackWins) / games * 100
 		}
+		stats = append(stats, s)
 	}
-	b.Positions[key] = append(b.Positions[key], Entry{Move: m, Weight: 1})
+	return stats
 }
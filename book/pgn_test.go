@@ -2,6 +2,8 @@ package book
 
 import (
 	"github.com/reecer/chess/pgn"
+	"github.com/reecer/chess/position"
+	"github.com/reecer/chess/position/move"
 	"strings"
 	"testing"
 )
@@ -27,3 +29,41 @@ func TestCorrectNumber(t *testing.T) {
 		}
 	}
 }
+
+func TestStats(t *testing.T) {
+	input := `[Event "one"]
+[Result "1-0"]
+
+1. e2e4 e7e5 2. d1h5 e8e7 3. h5e5 1-0
+
+[Event "two"]
+[Result "1/2-1/2"]
+
+1. e2e4 c7c5 2. g1f3 d7d6 1/2-1/2
+
+[Event "three"]
+[Result "*"]
+
+1. d2d4 d7d5 2. c2c4 e7e6 *
+`
+	pgns, _ := pgn.Read(strings.NewReader(input))
+	book, _ := FromPGN(pgns, 3)
+	stats := book.Stats(position.New())
+	if len(stats) != 2 {
+		t.Fatal(stats)
+	}
+	e4 := stats[0]
+	if e4.Move != move.Parse("e2e4") || e4.Count != 2 || e4.WhiteWins != 50 || e4.Draws != 50 || e4.BlackWins != 0 {
+		t.Error(e4)
+	}
+	p, _ := position.New().ApplyUCI("e2e4")
+	stats = book.Stats(p)
+	for _, s := range stats {
+		if s.Move == move.Parse("e7e5") && (s.Count != 1 || s.WhiteWins != 100) {
+			t.Error("e7e5 should have one white win but got", s)
+		}
+	}
+	if d4 := book.Stats(position.New())[1]; d4.Count != 1 || d4.WhiteWins+d4.Draws+d4.BlackWins != 0 {
+		t.Error("the result of the d4 game is unknown", d4)
+	}
+}
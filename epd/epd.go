@@ -11,7 +11,10 @@ import (
 	"github.com/reecer/chess/fen"
 	"github.com/reecer/chess/game"
 	"github.com/reecer/chess/position"
+	"github.com/reecer/chess/position/move"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -86,28 +89,295 @@ func Decode(epd string) (*EPD, error) {
 	return &EPD{Position: p, Operations: opers}, nil
 }
 
-// ToGame returns a game based on the position in the EPD provided.
+// Operand returns the operand of the first operation with the given opcode.
+func (e EPD) Operand(code string) (string, bool) {
+	for _, op := range e.Operations {
+		if op.Code == code {
+			return op.Operand, true
+		}
+	}
+	return "", false
+}
+
+// Filter returns a copy of the EPD with only the operations whose opcode is
+// one of the given codes, in their original order.
+func (e EPD) Filter(codes ...string) *EPD {
+	return e.keep(func(code string) bool { return hasCode(codes, code) })
+}
+
+// Without returns a copy of the EPD without the operations whose opcode is one
+// of the given codes, such as engine output like acn and acs.
+func (e EPD) Without(codes ...string) *EPD {
+	return e.keep(func(code string) bool { return !hasCode(codes, code) })
+}
+
+func (e EPD) keep(include func(code string) bool) *EPD {
+	n := &EPD{Position: position.Copy(e.Position)}
+	for _, op := range e.Operations {
+		if include(op.Code) {
+			n.Operations = append(n.Operations, op)
+		}
+	}
+	return n
+}
+
+func hasCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// PV returns the moves of the predicted variation (pv) played one after
+// another from the EPD's position. The moves can be in SAN or coordinate
+// notation. The error says which move could not be played.
+func (e EPD) PV() ([]move.Move, error) {
+	operand, ok := e.Operand("pv")
+	if !ok {
+		return nil, errors.New("epd: no pv operation")
+	}
+	p := position.Copy(e.Position)
+	var pv []move.Move
+	for i, s := range strings.Fields(operand) {
+		m, err := p.ParseLegalMove(s)
+		if err != nil {
+			return nil, errors.New("epd: pv move " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		p.MakeMove(m)
+		pv = append(pv, m)
+	}
+	return pv, nil
+}
+
+// Encode returns the EPD as a string with the operations in the order that
+// they are in Operations.
+func Encode(e *EPD) (string, error) {
+	return encode(e.Position, e.Operations)
+}
+
+// EncodeCanonical is like Encode but always writes the operations in the same
+// order: id, bm and am first followed by the rest sorted by opcode. EPDs with
+// the same operations encode the same no matter what order they were added in.
+func EncodeCanonical(e *EPD) (string, error) {
+	ops := make([]Operation, len(e.Operations))
+	copy(ops, e.Operations)
+	rank := func(code string) int {
+		switch code {
+		case "id":
+			return 0
+		case "bm":
+			return 1
+		case "am":
+			return 2
+		}
+		return 3
+	}
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ri, rj := rank(ops[i].Code), rank(ops[j].Code); ri != rj {
+			return ri < rj
+		}
+		return ops[i].Code < ops[j].Code
+	})
+	return encode(e.Position, ops)
+}
+
+func encode(p *position.Position, ops []Operation) (string, error) {
+	f, err := fen.Encode(p)
+	if err != nil {
+		return "", err
+	}
+	// An EPD only has the first four fields of a FEN:
+	epd := strings.Join(strings.Fields(f)[:4], " ")
+	for _, op := range ops {
+		epd += " " + op.Code
+		if operand := strings.TrimSpace(op.Operand); operand != "" {
+			epd += " " + operand
+		}
+		epd += ";"
+	}
+	return epd, nil
+}
+
+// Validate checks that the position of each EPD is legal and that the moves
+// of any bm and am operations are legal in it. An error is returned for each
+// EPD that fails, identified by its id (or its index if it has none).
+func Validate(epds []*EPD) []error {
+	var errs []error
+	for i, e := range epds {
+		name, ok := e.Operand("id")
+		if !ok {
+			name = "#" + strconv.Itoa(i)
+		}
+		if err := e.validate(); err != nil {
+			errs = append(errs, errors.New("epd "+name+": "+err.Error()))
+		}
+	}
+	return errs
+}
+
+func (e EPD) validate() error {
+	if e.Position == nil {
+		return errors.New("no position")
+	}
+	if err := e.Position.IsLegal(); err != nil {
+		return err
+	}
+	for _, op := range e.Operations {
+		if op.Code != "bm" && op.Code != "am" {
+			continue
+		}
+		for _, s := range strings.Fields(op.Operand) {
+			if _, err := e.Position.ParseLegalMove(s); err != nil {
+				return errors.New(op.Code + ": " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// SuiteStats summarizes the results that an engine wrote into an EPD suite.
+type SuiteStats struct {
+	Positions int
+	// Searched is the number of positions with an acd (analysis count depth)
+	// operation and AverageDepth is the average of them.
+	Searched     int
+	AverageDepth float64
+	// Evaluated is the number of positions with a ce (centipawn evaluation)
+	// operation and AverageEval is the average of them.
+	Evaluated   int
+	AverageEval float64
+	// Attempted is the number of positions with a bm or am operation that
+	// also have the engine's move in sm (supplied move) or pm (predicted
+	// move). A position is Solved if that move is one of the best moves and
+	// none of the moves to avoid.
+	Attempted     int
+	Solved        int
+	PercentSolved float64
+}
+
+// Analyze reads the results of an engine out of the operations of a suite.
+func Analyze(epds []*EPD) SuiteStats {
+	var stats SuiteStats
+	var depth, eval int
+	for _, e := range epds {
+		stats.Positions++
+		if n, err := strconv.Atoi(operand(e, "acd")); err == nil {
+			stats.Searched++
+			depth += n
+		}
+		if n, err := strconv.Atoi(operand(e, "ce")); err == nil {
+			stats.Evaluated++
+			eval += n
+		}
+		played := operand(e, "sm")
+		if played == "" {
+			played = operand(e, "pm")
+		}
+		best, avoid := operand(e, "bm"), operand(e, "am")
+		if played == "" || (best == "" && avoid == "") {
+			continue
+		}
+		stats.Attempted++
+		if (best == "" || e.contains(best, played)) && !e.contains(avoid, played) {
+			stats.Solved++
+		}
+	}
+	if stats.Searched > 0 {
+		stats.AverageDepth = float64(depth) / float64(stats.Searched)
+	}
+	if stats.Evaluated > 0 {
+		stats.AverageEval = float64(eval) / float64(stats.Evaluated)
+	}
+	if stats.Attempted > 0 {
+		stats.PercentSolved = float64(stats.Solved) / float64(stats.Attempted) * 100
+	}
+	return stats
+}
+
+func operand(e *EPD, code string) string {
+	o, _ := e.Operand(code)
+	return strings.TrimSpace(o)
+}
+
+// contains returns whether or not the move is in the list of moves. They are
+// compared as moves in the EPD's position so that "Nf3" matches "g1f3".
+func (e EPD) contains(moves, m string) bool {
+	target, err := e.Position.ParseLegalMove(m)
+	for _, s := range strings.Fields(moves) {
+		if s == m {
+			return true
+		}
+		if err != nil {
+			continue
+		}
+		if other, err := e.Position.ParseLegalMove(s); err == nil && other == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ToGame returns a game based on the position in the EPD provided. If the
+// EPD has a repetition count (rc), the game starts with it so that threefold
+// repetition takes the earlier occurrences into account.
 func (e EPD) ToGame() *game.Game {
 	g := game.New()
 	g.Position = e.Position
+	if rc, ok := e.Operand("rc"); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(rc)); err == nil {
+			g.SetRepetitionCount(n)
+		}
+	}
 	return g
 }
 
-// Read loads a file with multiple EPD's. Each EPD needs to be on its own line.
+// Read loads a file with multiple EPD's. Each EPD starts on its own line.
+// Blank lines between EPD's and lines starting with # (comments) are skipped.
+// A line that starts with a space or a tab continues the EPD before it, unless
+// its first field is a board (it has a '/'), so a long list of operations can
+// be wrapped while indented records are still read on their own:
+//
+//	1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+;
+//	    id "BK.01";
 func Read(file io.Reader) ([]*EPD, error) {
 	scanner := bufio.NewScanner(file)
 	var ret []*EPD
+	pending := ""
+	flush := func() error {
+		if pending == "" {
+			return nil
+		}
+		epd, err := Decode(pending)
+		if err != nil {
+			return err
+		}
+		ret = append(ret, epd)
+		pending = ""
+		return nil
+	}
 	for scanner.Scan() {
 		line := scanner.Text()
 		trimmed := strings.TrimSpace(line)
-		// Check for comments:
-		if len(trimmed) > 0 && trimmed[0] != '#' {
-			epd, err := Decode(trimmed)
-			if err != nil {
+		switch {
+		case len(trimmed) == 0:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case trimmed[0] == '#':
+			// Comments are skipped.
+		case pending != "" && (line[0] == ' ' || line[0] == '\t') && !strings.Contains(strings.Fields(trimmed)[0], "/"):
+			pending += " " + trimmed
+		default:
+			if err := flush(); err != nil {
 				return nil, err
 			}
-			ret = append(ret, epd)
+			pending = trimmed
 		}
 	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
 	return ret, nil
 }
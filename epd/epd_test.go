@@ -1,6 +1,11 @@
 package epd
 
 import (
+	"github.com/reecer/chess/game"
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"github.com/reecer/chess/position/square"
+	"strings"
 	"testing"
 )
 
@@ -18,3 +23,162 @@ func TestParseEPD(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestEncodeEPD(t *testing.T) {
+	test := "1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id \"BK.01\";"
+	epd, _ := Decode(test)
+	if s, err := Encode(epd); err != nil || s != test {
+		t.Error("wanted", test, "but got", s, err)
+	}
+}
+
+func TestEncodeCanonical(t *testing.T) {
+	a, _ := Decode("1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - c0 \"comment\"; ce 300; bm Qd1+; id \"BK.01\";")
+	b, _ := Decode("1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - id \"BK.01\";  ce 300; c0 \"comment\";bm   Qd1+;")
+	sa, _ := EncodeCanonical(a)
+	sb, _ := EncodeCanonical(b)
+	expected := "1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - id \"BK.01\"; bm Qd1+; c0 \"comment\"; ce 300;"
+	if sa != expected || sb != expected {
+		t.Error("wanted", expected, "but got", sa, "and", sb)
+	}
+}
+
+func TestPV(t *testing.T) {
+	epd, _ := Decode("1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; pv Qd1+ Kxd1;")
+	pv, err := epd.PV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pv) != 2 || pv[0] != move.Parse("d6d1") || pv[1] != move.Parse("c1d1") {
+		t.Error(pv)
+	}
+	epd, _ = Decode("1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - pv Qd1+ Kb1;")
+	if _, err := epd.PV(); err == nil || !strings.Contains(err.Error(), "move 1") {
+		t.Error("Kb1 does not get out of check but got", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	suite := `1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "BK.01";
+3r1k2/4npp1/1ppr3p/p6P/P2PPPP1/1NR5/5K2/2R5 w - - bm d5; id "BK.02";
+1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K1K3 b - - bm Qd1+; id "BK.03";
+2q1rr1k/3bbnnp/p2p1pp1/2pPp3/PpP1P1P1/1P2BNNP/2BQ1PRK/7R b - - bm Qh4; id "BK.04";
+`
+	epds, err := Read(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := Validate(epds)
+	if len(errs) != 2 {
+		t.Fatal("wanted 2 errors but got", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "BK.03") || !strings.Contains(errs[1].Error(), "BK.04") {
+		t.Error(errs)
+	}
+}
+
+func TestRepetitionCount(t *testing.T) {
+	repeat := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	play := func(epd string) game.GameStatus {
+		e, _ := Decode(epd)
+		g := e.ToGame()
+		var status game.GameStatus
+		for _, m := range repeat {
+			status = g.MakeMove(move.Parse(m))
+		}
+		return status
+	}
+	if status := play("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - rc 2;"); status != game.Threefold {
+		t.Error("the third repetition should be a draw but got", status)
+	}
+	if status := play("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"); status != game.InProgress {
+		t.Error("without rc this is only the second time but got", status)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	suite := `1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "BK.01"; acd 10; ce 300; sm d6d1;
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4 d4; id "start"; acd 20; ce 20; pm Nf3;
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - am f3; id "avoid"; pm g1f3;
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4; id "unplayed";
+`
+	epds, _ := Read(strings.NewReader(suite))
+	stats := Analyze(epds)
+	if stats.Positions != 4 || stats.Searched != 2 || stats.AverageDepth != 15 || stats.Evaluated != 2 || stats.AverageEval != 160 {
+		t.Error(stats)
+	}
+	if stats.Attempted != 3 || stats.Solved != 2 {
+		t.Error("BK.01 and avoid are solved, start is not:", stats)
+	}
+}
+
+func TestReadSeparatorsAndContinuations(t *testing.T) {
+	suite := `# Bratko-Kopec
+
+1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "BK.01";
+
+
+3r1k2/4npp1/1ppr3p/p6P/P2PPPP1/1NR5/5K2/2R5 w - - bm d5;
+    id "BK.02";
+	c0 "wrapped with a tab";
+2q1rr1k/3bbnnp/p2p1pp1/2pPp3/PpP1P1P1/1P2BNNP/2BQ1PRK/7R b - - bm f5; id "BK.04";
+`
+	epds, err := Read(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(epds) != 3 {
+		t.Fatal("wanted 3 EPDs but got", len(epds))
+	}
+	if id, _ := epds[1].Operand("id"); id != `"BK.02"` {
+		t.Error("the id should be read from the continuation line but got", id)
+	}
+	if c0, _ := epds[1].Operand("c0"); c0 != `"wrapped with a tab"` {
+		t.Error(c0)
+	}
+	if id, _ := epds[2].Operand("id"); id != `"BK.04"` {
+		t.Error(id)
+	}
+}
+
+func TestReadIndentedRecords(t *testing.T) {
+	suite := `  1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "BK.01";
+  3r1k2/4npp1/1ppr3p/p6P/P2PPPP1/1NR5/5K2/2R5 w - - bm d5;
+      id "BK.02";
+	2q1rr1k/3bbnnp/p2p1pp1/2pPp3/PpP1P1P1/1P2BNNP/2BQ1PRK/7R b - - bm f5; id "BK.04";
+`
+	epds, err := Read(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(epds) != 3 {
+		t.Fatal("indented boards should start their own EPD but got", len(epds))
+	}
+	for i, want := range []string{`"BK.01"`, `"BK.02"`, `"BK.04"`} {
+		if id, _ := epds[i].Operand("id"); id != want {
+			t.Error("wanted", want, "but got", id)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	e, err := Decode(`1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - acn 12345; bm Qd1+; acs 3; id "BK.01"; ce 300;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := e.Filter("id", "bm")
+	if len(kept.Operations) != 2 || kept.Operations[0].Code != "bm" || kept.Operations[1].Code != "id" {
+		t.Error("wanted bm and id in order but got", kept.Operations)
+	}
+	without := e.Without("acn", "acs")
+	if len(without.Operations) != 3 || without.Operations[0].Code != "bm" || without.Operations[2].Code != "ce" {
+		t.Error("wanted bm, id and ce but got", without.Operations)
+	}
+	if len(e.Operations) != 5 {
+		t.Error("the original EPD should be left alone", e.Operations)
+	}
+	kept.Position.ClearSquare(square.B8)
+	if e.Position.OnSquare(square.B8).Type == piece.None {
+		t.Error("the copy should have its own position")
+	}
+}
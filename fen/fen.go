@@ -49,7 +49,7 @@ func Encode(p *position.Position) (fen string, err error) {
 		enPas = fmt.Sprint(p.EnPassant)
 	}
 	// Moves and 50 move rule
-	fifty := strconv.Itoa(int(p.FiftyMoveCount / 2))
+	fifty := strconv.Itoa(int(p.FiftyMoveCount))
 	move := strconv.Itoa(p.MoveNumber)
 	// all together:
 	fen = boardstr + " " + turn + " " + rights + " " + enPas + " " + fifty + " " + move
@@ -77,11 +77,134 @@ func Decode(fen string) (*position.Position, error) {
 		p.ActiveColor = piece.Black
 	}
 	p.CastlingRights = parseCastlingRights(words[2])
-	p.EnPassant = parseEnPassantSquare(words[3])
+	p.EnPassant, err = parseEnPassantSquare(words[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// DecodeStrict is like Decode but returns an error if the en passant square
+// is one that no pawn can legally capture on or if a castling right is set
+// without the king and the rook on their starting squares.
+func DecodeStrict(fen string) (*position.Position, error) {
+	p, err := Decode(fen)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEnPassant(p); err != nil {
+		return nil, err
+	}
+	if err := checkCastling(p, false); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
 
+// DecodeLenient is like Decode but clears the en passant square if no pawn
+// can legally capture on it. Many tools write the square after every double
+// pawn push whether or not a capture is possible. Castling rights that are
+// impossible are dropped as well.
+func DecodeLenient(fen string) (*position.Position, error) {
+	p, err := Decode(fen)
+	if err != nil {
+		return nil, err
+	}
+	if checkEnPassant(p) != nil {
+		p.EnPassant = square.NoSquare
+	}
+	checkCastling(p, true)
 	return p, nil
 }
 
+// checkEnPassant returns an error if a pawn could not have just skipped over
+// the en passant square or if no pawn can legally capture on it.
+func checkEnPassant(p *position.Position) error {
+	if p.EnPassant == square.NoSquare {
+		return nil
+	}
+	if err := p.SetEnPassant(p.EnPassant, true); err != nil {
+		return errors.New("FEN: " + err.Error())
+	}
+	for m := range p.LegalMoves() {
+		if m.To() == p.EnPassant && p.OnSquare(m.From()).Type == piece.Pawn {
+			return nil
+		}
+	}
+	return errors.New("FEN: no pawn can capture en passant on " + p.EnPassant.String())
+}
+
+// Normalize decodes the FEN and encodes it again so that FENs of the same
+// position written by different tools come out the same. Extra whitespace is
+// removed, an en passant square that no pawn can capture on and impossible
+// castling rights are dropped as in DecodeLenient, and missing move counters
+// default to "0 1".
+func Normalize(fen string) (string, error) {
+	p, err := DecodeLenient(strings.Join(strings.Fields(fen), " "))
+	if err != nil {
+		return "", err
+	}
+	return Encode(p)
+}
+
+// Verify plays the moves, in SAN or coordinate notation, from the starting
+// FEN and returns an error if the position they reach is not the expected
+// one. Both FENs are compared after Normalize. If the expected FEN leaves out
+// the move counters, they are not compared. The error says which move could
+// not be played or how the positions differ.
+func Verify(startFEN string, moves []string, expectedFEN string) error {
+	p, err := Decode(strings.Join(strings.Fields(startFEN), " "))
+	if err != nil {
+		return err
+	}
+	for i, s := range moves {
+		m, err := p.ParseLegalMove(s)
+		if err != nil {
+			return errors.New("FEN: move " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		p.MakeMove(m)
+	}
+	expected, err := Normalize(expectedFEN)
+	if err != nil {
+		return err
+	}
+	reached, _ := Encode(p)
+	if reached, err = Normalize(reached); err != nil {
+		return err
+	}
+	if len(strings.Fields(expectedFEN)) < 6 {
+		expected = strings.Join(strings.Fields(expected)[:4], " ")
+		reached = strings.Join(strings.Fields(reached)[:4], " ")
+	}
+	if reached != expected {
+		return errors.New("FEN: the moves reach '" + reached + "' instead of '" + expected + "'")
+	}
+	return nil
+}
+
+// checkCastling returns an error naming the first castling right that is set
+// without the king and the rook on their starting squares. If clear is set,
+// every such right is taken away instead. Only the standard chess starting
+// squares are checked since Chess960 rights can not be read from a FEN here.
+func checkCastling(p *position.Position, clear bool) error {
+	rights := [2][2]string{{"K", "Q"}, {"k", "q"}}
+	for c := piece.White; c <= piece.Black; c++ {
+		for side := position.ShortSide; side <= position.LongSide; side++ {
+			if !p.CastlingRights[c][side] {
+				continue
+			}
+			if err := p.SetCastlingRight(c, side, true); err != nil {
+				if !clear {
+					return errors.New("FEN: castling right '" + rights[c][side] + "' is impossible: " + err.Error())
+				}
+				p.CastlingRights[c][side] = false
+			}
+		}
+	}
+	return nil
+}
+
 /*
 // DecodeToGame converts a fen string into a game and sets the appropriate tags.
 func DecodeToGame(fen string) (*game.Game, error) {
@@ -109,16 +232,25 @@ func appendMoveHistory(activeColor, moveCount, fiftyMoveCount string, pos *posit
 	if err != nil {
 		return errors.New("FEN: could not parse fifty move rule count")
 	}
-	// Since internally we store half moves:
-	pos.FiftyMoveCount = (fmc * 2) + map[string]uint64{"w": 0, "b": 1}[activeColor]
+	// The FEN counts half moves just like we do internally:
+	pos.FiftyMoveCount = fmc
 	return nil
 }
 
-func parseEnPassantSquare(sq string) square.Square {
-	if sq != "-" {
-		return square.Parse(sq)
+// parseEnPassantSquare reads the en passant field. Some tools write the
+// square in uppercase (E6) so the case is ignored.
+func parseEnPassantSquare(sq string) (square.Square, error) {
+	if sq == "-" {
+		return square.NoSquare, nil
+	}
+	sq = strings.ToLower(sq)
+	if len(sq) != 2 || sq[0] < 'a' || sq[0] > 'h' {
+		return square.NoSquare, errors.New("FEN: invalid en passant square '" + sq + "'")
+	}
+	if sq[1] != '3' && sq[1] != '6' {
+		return square.NoSquare, errors.New("FEN: en passant square '" + sq + "' is not on the 3rd or 6th rank")
 	}
-	return square.NoSquare
+	return square.Parse(sq), nil
 }
 
 func parseCastlingRights(KQkq string) [2][2]bool {
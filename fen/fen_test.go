@@ -1,7 +1,6 @@
 package fen
 
 import (
-	"github.com/reecer/chess/game"
 	"github.com/reecer/chess/piece"
 	"github.com/reecer/chess/position"
 	"github.com/reecer/chess/position/move"
@@ -47,6 +46,21 @@ func TestFENenPassant(t *testing.T) {
 	}
 }
 
+func TestFENenPassantUppercase(t *testing.T) {
+	fen := "rnbqkbnr/pppp1ppp/8/3Pp3/8/8/PPP1PPPP/RNBQKBNR w KQkq E6 0 3"
+	g, err := Decode(fen)
+	if err != nil || g.EnPassant != square.E6 {
+		t.Error("E6 should be read as e6", err)
+	}
+}
+
+func TestFENenPassantWrongRank(t *testing.T) {
+	fen := "rnbqkbnr/pppp1ppp/8/3Pp3/8/8/PPP1PPPP/RNBQKBNR w KQkq e5 0 3"
+	if _, err := Decode(fen); err == nil {
+		t.Error("en passant can only be on the 3rd or 6th rank")
+	}
+}
+
 func TestFENCastlingRights(t *testing.T) {
 	fen := "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"
 	p, _ := Decode(fen)
@@ -58,8 +72,8 @@ func TestFENCastlingRights(t *testing.T) {
 }
 
 func TestFENMarshalRoot(t *testing.T) {
-	g := game.New()
-	fen, _ := Encode(g.Position)
+	p := position.New()
+	fen, _ := Encode(p)
 	root := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 	if fen != root {
 		t.Fail()
@@ -67,8 +81,8 @@ func TestFENMarshalRoot(t *testing.T) {
 }
 
 func TestFENWhitesMove(t *testing.T) {
-	g := game.New()
-	fen, _ := Encode(g.Position)
+	p := position.New()
+	fen, _ := Encode(p)
 	player := strings.Split(fen, " ")[1]
 	if player != "w" {
 		t.Fail()
@@ -76,11 +90,104 @@ func TestFENWhitesMove(t *testing.T) {
 }
 
 func TestFENBlacksMove(t *testing.T) {
-	g := game.New()
-	g.MakeMove(move.Parse("e2e4"))
-	fen, _ := Encode(g.Position)
+	p := position.New()
+	p.MakeMove(move.Parse("e2e4"))
+	fen, _ := Encode(p)
 	player := strings.Split(fen, " ")[1]
 	if player != "b" {
 		t.Fail()
 	}
 }
+
+func TestEncodeEditedPosition(t *testing.T) {
+	p := position.New()
+	p.Clear()
+	p.Put(piece.New(piece.White, piece.King), square.E1)
+	p.Put(piece.New(piece.White, piece.Rook), square.H1)
+	p.Put(piece.New(piece.Black, piece.King), square.E8)
+	p.Put(piece.New(piece.Black, piece.Pawn), square.E4)
+	p.Put(piece.New(piece.White, piece.Pawn), square.D4)
+	p.SetSideToMove(piece.Black)
+	// Clear doesn't touch the castling rights:
+	for c := piece.White; c <= piece.Black; c++ {
+		p.SetCastlingRight(c, position.ShortSide, false)
+		p.SetCastlingRight(c, position.LongSide, false)
+	}
+	if err := p.SetCastlingRight(piece.White, position.ShortSide, true); err != nil {
+		t.Error(err)
+	}
+	if err := p.SetEnPassant(square.D3, true); err != nil {
+		t.Error(err)
+	}
+	fen, _ := Encode(p)
+	if fen != "4k3/8/8/8/3Pp3/8/8/4K2R b K d3 0 1" {
+		t.Error(fen)
+	}
+}
+
+func TestDecodePhantomEnPassant(t *testing.T) {
+	// no white pawn can take on c6:
+	phantom := "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"
+	if _, err := DecodeStrict(phantom); err == nil {
+		t.Error("strict decoding should reject c6")
+	}
+	p, err := DecodeLenient(phantom)
+	if err != nil || p.EnPassant != square.NoSquare {
+		t.Error("lenient decoding should clear c6", err)
+	}
+	real := "rnbqkbnr/pp1ppppp/8/2pP4/8/8/PPP1PPPP/RNBQKBNR w KQkq c6 0 3"
+	for _, decode := range []func(string) (*position.Position, error){DecodeStrict, DecodeLenient} {
+		if p, err := decode(real); err != nil || p.EnPassant != square.C6 {
+			t.Error("d5 can take on c6", err)
+		}
+	}
+}
+
+func TestDecodeImpossibleCastling(t *testing.T) {
+	// the rook on h1 is gone but white still claims K:
+	noRook := "r3k2r/8/8/8/8/8/8/R3K3 w KQkq - 0 1"
+	_, err := DecodeStrict(noRook)
+	if err == nil || !strings.Contains(err.Error(), "'K'") {
+		t.Error("the K right should be flagged but got", err)
+	}
+	p, err := DecodeLenient(noRook)
+	if err != nil || p.CastlingRights != [2][2]bool{{false, true}, {true, true}} {
+		t.Error("lenient decoding should only drop K", p.CastlingRights, err)
+	}
+	if _, err := DecodeStrict("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	expected := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	for _, f := range []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+		"  rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR   b KQkq - 0   1 ",
+	} {
+		if got, err := Normalize(f); err != nil || got != expected {
+			t.Error(f, "should normalize to", expected, "but got", got, err)
+		}
+	}
+	if _, err := Normalize("rnbqkbnr/pppppppp/8/8"); err == nil {
+		t.Error("an incomplete FEN can not be normalized")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	start := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	mated := "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	if err := Verify(start, []string{"f3", "e5", "g2g4", "Qh4#"}, mated); err != nil {
+		t.Error(err)
+	}
+	if err := Verify(start, []string{"f3", "e5", "g4", "Qh4"}, "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq -"); err != nil {
+		t.Error("the counters should not be compared when they are left out", err)
+	}
+	if err := Verify(start, []string{"f3", "e5", "g4", "Qf6"}, mated); err == nil || !strings.Contains(err.Error(), "instead of") {
+		t.Error("Qf6 does not reach the expected position but got", err)
+	}
+	if err := Verify(start, []string{"f3", "e5", "Kf2", "Qh4", "e4"}, mated); err == nil || !strings.Contains(err.Error(), "move 4") {
+		t.Error("e4 is illegal once in check, wanted move 4 but got", err)
+	}
+}
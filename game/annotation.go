@@ -0,0 +1,59 @@
+package game
+
+import (
+	"time"
+)
+
+// SetMoveComment sets the comment on the move at the given ply. An empty
+// comment removes it.
+func (G *Game) SetMoveComment(ply int, text string) {
+	if text == "" {
+		delete(G.comments, ply)
+		return
+	}
+	if G.comments == nil {
+		G.comments = make(map[int]string)
+	}
+	G.comments[ply] = text
+}
+
+// MoveComment returns the comment on the move at the given ply, or "" if
+// there is none.
+func (G *Game) MoveComment(ply int) string {
+	return G.comments[ply]
+}
+
+// SetMoveNAG sets the Numeric Annotation Glyphs of the move at the given ply,
+// such as 1 for a good move (!) or 4 for a blunder (??). It replaces any that
+// were set before and no glyphs removes them.
+func (G *Game) SetMoveNAG(ply int, nags []int) {
+	if len(nags) == 0 {
+		delete(G.nags, ply)
+		return
+	}
+	if G.nags == nil {
+		G.nags = make(map[int][]int)
+	}
+	G.nags[ply] = append([]int(nil), nags...)
+}
+
+// MoveNAG returns the Numeric Annotation Glyphs of the move at the given ply.
+func (G *Game) MoveNAG(ply int) []int {
+	return append([]int(nil), G.nags[ply]...)
+}
+
+// SetMoveClock sets the time that was left on the mover's clock after the
+// move at the given ply, as given by a [%clk] command.
+func (G *Game) SetMoveClock(ply int, left time.Duration) {
+	if G.clocks == nil {
+		G.clocks = make(map[int]time.Duration)
+	}
+	G.clocks[ply] = left
+}
+
+// MoveClock returns the time that was left on the mover's clock after the
+// move at the given ply and whether or not it was set.
+func (G *Game) MoveClock(ply int) (time.Duration, bool) {
+	left, ok := G.clocks[ply]
+	return left, ok
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"github.com/reecer/chess/position"
+	"github.com/reecer/chess/position/move"
+	"strconv"
+	"strings"
+)
+
+// Score is an engine evaluation from white's point of view. If Mate is not
+// zero, then there is a forced mate in that many moves and Centipawns is
+// ignored. A negative Mate means black is the one mating.
+type Score struct {
+	Centipawns int
+	Mate       int
+}
+
+// String returns the score the way it is written in an eval command.
+// ex: 0.35, -1.20, #3, #-2
+func (s Score) String() string {
+	if s.Mate != 0 {
+		return "#" + strconv.Itoa(s.Mate)
+	}
+	sign := ""
+	cp := s.Centipawns
+	if cp < 0 {
+		sign = "-"
+		cp = -cp
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cp/100, cp%100)
+}
+
+// ParseScore reads a score written in pawns (0.35) or as a mate (#-2).
+// Anything after a comma is ignored since some tools append the search
+// depth: 0.35,22
+func ParseScore(s string) (Score, error) {
+	s = strings.TrimSpace(strings.Split(s, ",")[0])
+	if strings.HasPrefix(s, "#") {
+		mate, err := strconv.Atoi(strings.TrimPrefix(s[1:], "+"))
+		if err != nil || mate == 0 {
+			return Score{}, errors.New("could not parse mate score '" + s + "'")
+		}
+		return Score{Mate: mate}, nil
+	}
+	pawns, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Score{}, errors.New("could not parse score '" + s + "'")
+	}
+	if pawns < 0 {
+		return Score{Centipawns: int(pawns*100 - 0.5)}, nil
+	}
+	return Score{Centipawns: int(pawns*100 + 0.5)}, nil
+}
+
+// PositionEval is a position of the game along with the evaluation of it, if
+// there is one.
+type PositionEval struct {
+	Pos     *position.Position
+	Eval    Score
+	HasEval bool
+}
+
+// SetEval stores the evaluation of the position after the move at the given
+// ply, such as one read from a PGN [%eval] comment.
+func (G *Game) SetEval(ply int, s Score) {
+	if G.evals == nil {
+		G.evals = make(map[int]Score)
+	}
+	G.evals[ply] = s
+}
+
+// Eval returns the evaluation of the position after the move at the given
+// ply, if one was set.
+func (G *Game) Eval(ply int) (Score, bool) {
+	s, ok := G.evals[ply]
+	return s, ok
+}
+
+// PositionsWithEval returns the starting position followed by the position
+// after each move along with its evaluation. The starting position never has
+// an evaluation. Moves that were rejected as illegal are left out.
+func (G *Game) PositionsWithEval() []PositionEval {
+	positions := []PositionEval{{Pos: G.positionAt(0)}}
+	G.replay(len(G.Moves), func(ply int, _ move.Move, _, after *position.Position) {
+		s, ok := G.Eval(ply)
+		positions = append(positions, PositionEval{Pos: position.Copy(after), Eval: s, HasEval: ok})
+	})
+	return positions
+}
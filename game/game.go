@@ -3,6 +3,7 @@ package game
 
 import (
 	"fmt"
+	"github.com/reecer/chess/fen"
 	"github.com/reecer/chess/piece"
 	"github.com/reecer/chess/polyglot"
 	"github.com/reecer/chess/position"
@@ -14,10 +15,31 @@ import (
 // Game represents a chess game.
 type Game struct {
 	Tags          map[string]string
+	tagOrder      []string
 	control       [2]TimeControl
 	Position      *position.Position
 	Moves         []move.Move
 	positionCache map[uint64]int
+	// startSeeded is set when the starting position's count was given with
+	// SetRepetitionCount.
+	startSeeded bool
+	// evals are the evaluations of the positions keyed by ply.
+	evals map[int]Score
+	// comments and nags are the annotations of the moves keyed by ply.
+	comments map[int]string
+	nags     map[int][]int
+	// clocks are the times left after the moves keyed by ply.
+	clocks map[int]time.Duration
+	// Variations are the alternative lines that branch off of Moves.
+	Variations []*Variation
+	// start is the position before the first move was made.
+	start *position.Position
+	// rejected holds the plies of Moves that MakeMove refused as illegal.
+	// They are recorded but were never made on the board.
+	rejected map[int]bool
+	// parent and variation are set on games returned by EnterVariation.
+	parent    *Game
+	variation *Variation
 }
 
 // New returns a fresh game with all of the pieces in the
@@ -41,6 +63,67 @@ func NewTimedGame(control [2]TimeControl) *Game {
 	return g
 }
 
+// SetTimeControl sets the time control of one player and starts their clock
+// over with it. Giving the players different controls sets up time odds.
+func (G *Game) SetTimeControl(player piece.Color, control TimeControl) {
+	G.control[player] = control
+	G.control[player].Clear()
+	G.control[player].Reset()
+}
+
+// NewHandicap returns a game that starts from the opening position with the
+// pieces on the given squares taken off the board, for playing with piece
+// odds. Castling rights that need a removed king or rook are dropped. The
+// starting position is recorded in the FEN tag.
+func NewHandicap(remove []square.Square) *Game {
+	g := New()
+	for _, sq := range remove {
+		g.Position.ClearSquare(sq)
+	}
+	for c := piece.White; c <= piece.Black; c++ {
+		for side := position.ShortSide; side <= position.LongSide; side++ {
+			if g.Position.SetCastlingRight(c, side, true) != nil {
+				g.Position.SetCastlingRight(c, side, false)
+			}
+		}
+	}
+	f, _ := fen.Encode(g.Position)
+	g.SetTag("FEN", f)
+	g.SetTag("Setup", "1")
+	return g
+}
+
+// SetTag sets one of the game's PGN tags. When the game is written out as a
+// PGN, tags that are not part of the Seven Tag Roster are written in the order
+// that they were first set.
+func (G *Game) SetTag(key, value string) {
+	if !G.tagSet(key) {
+		G.tagOrder = append(G.tagOrder, key)
+	}
+	G.Tags[key] = value
+}
+
+// TagOrder returns the keys of the tags that were set with SetTag in the
+// order they were first set.
+func (G *Game) TagOrder() []string {
+	var order []string
+	for _, key := range G.tagOrder {
+		if _, ok := G.Tags[key]; ok {
+			order = append(order, key)
+		}
+	}
+	return order
+}
+
+func (G *Game) tagSet(key string) bool {
+	for _, k := range G.tagOrder {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
 // ActiveColor returns the color of the player whos turn it is.
 func (G *Game) ActiveColor() piece.Color {
 	return G.Position.ActiveColor
@@ -77,7 +160,8 @@ func (G *Game) MakeTimedMove(m move.Move, timeTaken time.Duration) GameStatus {
 func (G *Game) MakeMove(m move.Move) GameStatus {
 	from, to, movingPiece, capturedPiece := G.decompose(m)
 	if G.illegalMove(movingPiece, m) {
-		defer func() { G.Moves = append(G.Moves, m) }()
+		G.captureStart()
+		defer G.reject(m)
 		return G.illegalMoveStatus()
 	}
 	G.makeMove(m, from, to, movingPiece, capturedPiece)
@@ -91,7 +175,25 @@ func (G *Game) decompose(m move.Move) (from, to square.Square, movingPiece, capt
 	return
 }
 
+// captureStart records the starting position before the first move, legal
+// or not, is added to Moves.
+func (G *Game) captureStart() {
+	if G.start == nil && len(G.Moves) == 0 {
+		G.start = position.Copy(G.Position)
+	}
+}
+
+// reject adds a move to Moves without making it on the board.
+func (G *Game) reject(m move.Move) {
+	if G.rejected == nil {
+		G.rejected = make(map[int]bool)
+	}
+	G.rejected[len(G.Moves)] = true
+	G.Moves = append(G.Moves, m)
+}
+
 func (G *Game) makeMove(m move.Move, from, to square.Square, movingPiece, capturedPiece piece.Piece) {
+	G.captureStart()
 	G.Position.MakeMove(m)
 	G.Moves = append(G.Moves, m)
 	G.cachePosition()
@@ -107,6 +209,12 @@ func (G *Game) Status() GameStatus {
 	if stale {
 		return Stalemate
 	}
+	if G.RepetitionCount() >= 5 {
+		return Fivefold
+	}
+	if G.Position.FiftyMoveCount >= 150 {
+		return SeventyFiveMoveRule
+	}
 	if G.threeFold() {
 		return Threefold
 	}
@@ -137,13 +245,33 @@ func (G *Game) illegalMoveStatus() GameStatus {
 }
 
 // TODO(reecer): threeFold detection should not have to go through all of the move history.
-// BUG(reecer): starting FEN is not considered when calculating threefold.
 func (G *Game) threeFold() bool {
+	return G.RepetitionCount() >= 3
+}
+
+// RepetitionCount returns how many times the current position has come up in
+// the game so far, counting this time, so it is 1 for a new position. The
+// starting position counts as having come up once unless its count was set
+// with SetRepetitionCount.
+func (G *Game) RepetitionCount() int {
 	hash := polyglot.Encode(G.Position)
-	if G.positionCache[hash] >= 3 {
-		return true
+	count := G.positionCache[hash]
+	if !G.startSeeded && hash == polyglot.Encode(G.positionAt(0)) {
+		count++
 	}
-	return false
+	return count
+}
+
+// SetRepetitionCount records that the current position has already come up n
+// times, counting this time. It is for continuing a game from a snapshot such
+// as an EPD with an rc operation, so that earlier repetitions count towards a
+// threefold repetition.
+func (G *Game) SetRepetitionCount(n int) {
+	hash := polyglot.Encode(G.Position)
+	if hash == polyglot.Encode(G.positionAt(0)) {
+		G.startSeeded = true
+	}
+	G.positionCache[hash] = n
 }
 
 func (G *Game) cachePosition() {
@@ -153,6 +281,25 @@ func (G *Game) cachePosition() {
 	G.positionCache[hash] = c
 }
 
+// SetEnPassantFromLastDoublePush sets the en passant square of the game's
+// position if the last move that was made, leaving out moves rejected as
+// illegal, was a double pawn push, such as after the position was edited by
+// hand. A position on its own can not tell if en
+// passant is possible since that depends on the move before it, which only
+// the game knows. It returns whether or not the square was set.
+func (G *Game) SetEnPassantFromLastDoublePush() bool {
+	played := G.PlayedMoves()
+	if len(played) == 0 {
+		return false
+	}
+	last := G.Moves[played[len(played)-1]]
+	from, to := int(last.From()), int(last.To())
+	if G.Position.OnSquare(last.To()).Type != piece.Pawn || (from-to != 16 && to-from != 16) {
+		return false
+	}
+	return G.Position.SetEnPassant(square.Square((from+to)/2), true) == nil
+}
+
 // Check returns whether or not the specified color is in check.
 func (G *Game) Check(color piece.Color) bool {
 	return G.Position.Check(color)
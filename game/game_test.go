@@ -262,3 +262,106 @@ func TestGameResultInProgress(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestGameRepetitionCount(t *testing.T) {
+	g := New()
+	if n := g.RepetitionCount(); n != 1 {
+		t.Error("the start position has come up once but got", n)
+	}
+	expected := []int{1, 1, 1, 2, 2}
+	for i, m := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3"} {
+		g.MakeMove(move.Parse(m))
+		if n := g.RepetitionCount(); n != expected[i] {
+			t.Error("after", m, "wanted", expected[i], "but got", n)
+		}
+	}
+}
+
+func TestFivefold(t *testing.T) {
+	g := New()
+	var status GameStatus
+	for i := 1; i <= 4; i++ {
+		for _, m := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			status = g.MakeMove(move.Parse(m))
+		}
+		if i < 2 && status != InProgress || i >= 2 && i < 4 && status != Threefold {
+			t.Error("after", i, "repetitions wanted a claimable draw at most but got", status)
+		}
+	}
+	if status != Fivefold || status&Draw == 0 {
+		t.Error("the fifth time the start position comes up should end the game but got", status)
+	}
+}
+
+func TestSeventyFiveMoveRule(t *testing.T) {
+	g := New()
+	g.Position.FiftyMoveCount = 148
+	if status := g.MakeMove(move.Parse("g1f3")); status != FiftyMoveRule {
+		t.Error("149 half moves is only claimable but got", status)
+	}
+	if status := g.MakeMove(move.Parse("g8f6")); status != SeventyFiveMoveRule {
+		t.Error("150 half moves should end the game but got", status)
+	}
+}
+
+func TestTimeOdds(t *testing.T) {
+	g := NewTimedGame([2]TimeControl{
+		NewTimeControl(5*time.Minute, 40, 0, false),
+		NewTimeControl(5*time.Minute, 40, 0, false),
+	})
+	g.SetTimeControl(piece.White, NewTimeControl(time.Minute, 40, 0, false))
+	if g.Clock(piece.White) != time.Minute || g.Clock(piece.Black) != 5*time.Minute {
+		t.Fatal("wanted 1 and 5 minutes but got", g.Clock(piece.White), g.Clock(piece.Black))
+	}
+	for _, m := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+		if s := g.MakeTimedMove(move.Parse(m), 25*time.Second); s != InProgress {
+			t.Fatal(m, "ended the game with", s)
+		}
+	}
+	if s := g.MakeTimedMove(move.Parse("f1c4"), 25*time.Second); s != WhiteTimedOut {
+		t.Error("white should flag first but got", s)
+	}
+}
+
+func TestNewHandicap(t *testing.T) {
+	g := NewHandicap([]square.Square{square.B1, square.A1})
+	if g.Position.OnSquare(square.B1).Type != piece.None || g.Position.OnSquare(square.A1).Type != piece.None {
+		t.Error("the knight and rook should be gone")
+	}
+	if g.Position.CastlingRights != [2][2]bool{{true, false}, {true, true}} {
+		t.Error("white can only castle short but got", g.Position.CastlingRights)
+	}
+	if g.Tags["FEN"] != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/2BQKBNR w Kkq - 0 1" {
+		t.Error(g.Tags["FEN"])
+	}
+	if s := g.MakeMove(move.Parse("e2e4")); s != InProgress {
+		t.Error(s)
+	}
+}
+
+func TestSetEnPassantFromLastDoublePush(t *testing.T) {
+	g := New()
+	if g.SetEnPassantFromLastDoublePush() {
+		t.Error("there is no last move")
+	}
+	g.MakeMove(move.Parse("e2e4"))
+	g.Position.SetEnPassant(square.NoSquare, false)
+	if !g.SetEnPassantFromLastDoublePush() || g.Position.EnPassant != square.E3 {
+		t.Error("e2e4 should give e3 but got", g.Position.EnPassant)
+	}
+	g.MakeMove(move.Parse("g8f6"))
+	if g.SetEnPassantFromLastDoublePush() || g.Position.EnPassant != square.NoSquare {
+		t.Error("a knight move has no en passant square but got", g.Position.EnPassant)
+	}
+	g.MakeMove(move.Parse("d2d4"))
+	g.MakeMove(move.Parse("f6f6"))
+	g.Position.SetEnPassant(square.NoSquare, false)
+	if !g.SetEnPassantFromLastDoublePush() || g.Position.EnPassant != square.D3 {
+		t.Error("the rejected move should be skipped for d2d4 but got", g.Position.EnPassant)
+	}
+	g = New()
+	g.MakeMove(move.Parse("e2e5"))
+	if g.SetEnPassantFromLastDoublePush() {
+		t.Error("the only move was rejected")
+	}
+}
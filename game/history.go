@@ -0,0 +1,104 @@
+package game
+
+import (
+	"encoding/binary"
+	"github.com/reecer/chess/fen"
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/polyglot"
+	"github.com/reecer/chess/position"
+	"github.com/reecer/chess/position/move"
+	"hash/fnv"
+	"strconv"
+)
+
+// FENHistory returns the FEN of the starting position followed by the FEN
+// of the position after each move of the game. Moves that were rejected as
+// illegal are left out.
+func (G *Game) FENHistory() []string {
+	start, _ := fen.Encode(G.positionAt(0))
+	history := []string{start}
+	G.replay(len(G.Moves), func(_ int, _ move.Move, _, after *position.Position) {
+		f, _ := fen.Encode(after)
+		history = append(history, f)
+	})
+	return history
+}
+
+// Fingerprint returns a hash of the starting position and the sequence of
+// moves played, for finding duplicate games. Games that transpose into the
+// same position with the moves in a different order do not match. Tags,
+// including the result, and moves that were rejected as illegal are not part
+// of the fingerprint.
+func (G *Game) Fingerprint() uint64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.BigEndian, polyglot.Encode(G.positionAt(0)))
+	G.replay(len(G.Moves), func(_ int, m move.Move, _, _ *position.Position) {
+		h.Write([]byte(m.String()))
+	})
+	return h.Sum64()
+}
+
+// Movetext returns the moves of the game in SAN with move numbers, followed by
+// the result, without any tags. Moves that were rejected as illegal are left
+// out. ex: 1. e4 e5 2. Qh5 Nc6 *
+func (G *Game) Movetext() string {
+	s := ""
+	G.replay(len(G.Moves), func(_ int, m move.Move, before, _ *position.Position) {
+		if before.ActiveColor == piece.White {
+			s += strconv.Itoa(before.MoveNumber) + ". "
+		} else if s == "" {
+			s += strconv.Itoa(before.MoveNumber) + "... "
+		}
+		s += before.SAN(m) + " "
+	})
+	return s + G.Result()
+}
+
+// The phases (see position.GamePhase) at or below which the middlegame and
+// the endgame are said to begin. The middlegame begins once roughly an exchange
+// of minor pieces or more has happened and the endgame once at most about a
+// rook and a minor piece each are left.
+const (
+	MiddlegamePhase = 20
+	EndgamePhase    = 8
+)
+
+// PlayedMoves returns the plies of Moves that were made on the board, leaving
+// out the moves that MakeMove rejected as illegal.
+func (G *Game) PlayedMoves() []int {
+	var played []int
+	for ply := range G.Moves {
+		if !G.rejected[ply] {
+			played = append(played, ply)
+		}
+	}
+	return played
+}
+
+// PlyCount returns the number of half moves played in the game.
+func (G *Game) PlyCount() int {
+	return len(G.Moves)
+}
+
+// OpeningMiddlegameEndgameBoundaries returns the plies at which the middlegame
+// and the endgame begin: the number of moves that had been played when the
+// phase of the position first dropped to MiddlegamePhase and EndgamePhase.
+// -1 is returned for a phase that the game never reached. Moves that were
+// rejected as illegal are skipped but still counted in the plies.
+func (G *Game) OpeningMiddlegameEndgameBoundaries() (mgPly, egPly int) {
+	mgPly, egPly = -1, -1
+	mark := func(ply int, p *position.Position) {
+		phase := p.GamePhase()
+		if mgPly == -1 && phase <= MiddlegamePhase {
+			mgPly = ply
+		}
+		if egPly == -1 && phase <= EndgamePhase {
+			egPly = ply
+		}
+	}
+	mark(0, G.positionAt(0))
+	G.replay(len(G.Moves), func(ply int, _ move.Move, _, after *position.Position) {
+		mark(ply+1, after)
+	})
+	return mgPly, egPly
+}
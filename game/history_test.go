@@ -0,0 +1,132 @@
+package game
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"testing"
+)
+
+func TestFENHistory(t *testing.T) {
+	g := New()
+	moves := []string{"e2e4", "e7e5", "g1f3"}
+	for _, m := range moves {
+		g.MakeMove(move.Parse(m))
+	}
+	history := g.FENHistory()
+	if len(history) != len(moves)+1 {
+		t.Fatal("wanted", len(moves)+1, "FENs but got", len(history))
+	}
+	if history[0] != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1" {
+		t.Error("first FEN should be the start position but got", history[0])
+	}
+	if last := history[len(history)-1]; last != "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2" {
+		t.Error(last)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	play := func(moves ...string) *Game {
+		g := New()
+		for _, m := range moves {
+			g.MakeMove(move.Parse(m))
+		}
+		return g
+	}
+	a := play("e2e4", "e7e5", "g1f3", "b8c6")
+	b := play("e2e4", "e7e5", "g1f3", "b8c6")
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("games with the same moves should have the same fingerprint")
+	}
+	// same position, different move order:
+	c := play("g1f3", "b8c6", "e2e4", "e7e5")
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("transpositions should not have the same fingerprint")
+	}
+	if a.Fingerprint() == play("e2e4", "e7e5").Fingerprint() {
+		t.Error("different games should not have the same fingerprint")
+	}
+	if a.Fingerprint() != play("e2e4", "e2e5", "e7e5", "g1f3", "b8c6").Fingerprint() {
+		t.Error("a rejected move should not change the fingerprint")
+	}
+}
+
+func TestPhaseBoundaries(t *testing.T) {
+	g := New()
+	if mg, eg := g.OpeningMiddlegameEndgameBoundaries(); mg != -1 || eg != -1 {
+		t.Error("the start position is the opening but got", mg, eg)
+	}
+	// 1. e4 d5 2. exd5 Qxd5 3. Nc3 Qxa2 4. Rxa2
+	for _, m := range []string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3", "d5a2", "a1a2"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if g.PlyCount() != 7 {
+		t.Error("wanted 7 plies but got", g.PlyCount())
+	}
+	if mg, eg := g.OpeningMiddlegameEndgameBoundaries(); mg != 7 || eg != -1 {
+		t.Error("the middlegame starts after the queen trade but got", mg, eg)
+	}
+	g = New()
+	for _, m := range []string{"e2e4", "d7d5", "e4d5", "d8d5", "e2e5", "b1c3", "d5a2", "a1a2"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if mg, eg := g.OpeningMiddlegameEndgameBoundaries(); mg != 8 || eg != -1 {
+		t.Error("a rejected move should be skipped but counted but got", mg, eg)
+	}
+}
+
+func TestMovetext(t *testing.T) {
+	g := New()
+	if text := g.Movetext(); text != "*" {
+		t.Error("a game without moves only has a result but got", text)
+	}
+	for _, m := range []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6", "h5f7"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if text := g.Movetext(); text != "1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0" {
+		t.Error(text)
+	}
+	g = New()
+	g.Position.ActiveColor = piece.Black
+	g.Position.MoveNumber = 12
+	g.MakeMove(move.Parse("e7e5"))
+	g.MakeMove(move.Parse("g1f3"))
+	if text := g.Movetext(); text != "12... e5 13. Nf3 *" {
+		t.Error("a game starting with black should number the first move 12... but got", text)
+	}
+	g = New()
+	for _, m := range []string{"e2e4", "e2e5", "e7e5"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if text := g.Movetext(); text != "1. e4 e5 *" {
+		t.Error("rejected moves should be left out but got", text)
+	}
+}
+
+func TestFENHistoryRejectedMove(t *testing.T) {
+	g := New()
+	for _, m := range []string{"e2e4", "e2e5", "e7e5"} {
+		g.MakeMove(move.Parse(m))
+	}
+	history := g.FENHistory()
+	if len(history) != 3 {
+		t.Fatal("wanted 3 FENs but got", len(history))
+	}
+	if last := history[2]; last != "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2" {
+		t.Error(last)
+	}
+}
+
+func TestPositionsWithEvalRejectedMove(t *testing.T) {
+	g := New()
+	for _, m := range []string{"e2e4", "e2e5", "e7e5"} {
+		g.MakeMove(move.Parse(m))
+	}
+	g.SetEval(2, Score{Centipawns: 20})
+	positions := g.PositionsWithEval()
+	if len(positions) != 3 {
+		t.Fatal("wanted 3 positions but got", len(positions))
+	}
+	if positions[1].HasEval || !positions[2].HasEval || positions[2].Eval.Centipawns != 20 {
+		t.Error("the evaluation of e5 should stay with the position after e5")
+	}
+}
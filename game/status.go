@@ -16,10 +16,14 @@ const (
 	FiftyMoveRule                   //1024
 	Stalemate                       //2048
 	InsufficientMaterial            //4096
+	// Fivefold and SeventyFiveMoveRule are the draws that end the game
+	// without either player having to claim them.
+	Fivefold            //8192
+	SeventyFiveMoveRule //16384
 )
 
 const (
 	WhiteWon GameStatus = (BlackCheckmated | BlackTimedOut | BlackResigned | BlackIllegalMove)
 	BlackWon GameStatus = (WhiteCheckmated | WhiteTimedOut | WhiteResigned | WhiteIllegalMove)
-	Draw     GameStatus = (Threefold | FiftyMoveRule | Stalemate | InsufficientMaterial)
+	Draw     GameStatus = (Threefold | FiftyMoveRule | Stalemate | InsufficientMaterial | Fivefold | SeventyFiveMoveRule)
 )
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"errors"
+	"github.com/reecer/chess/position"
+	"github.com/reecer/chess/position/move"
+	"strconv"
+)
+
+// MaxVariationDepth is how deeply variations can be nested. Code that walks
+// through variations stops at this depth so that a malformed tree can not make
+// it loop forever.
+const MaxVariationDepth = 64
+
+// Variation is an alternative line of play. Ply is the index of the move in
+// the line it branches off of that Moves[0] is played instead of. Plies are
+// always counted from the start of the game, so a variation that branches off
+// of another variation uses the same numbering as the mainline.
+type Variation struct {
+	Ply        int
+	Moves      []move.Move
+	Variations []*Variation
+}
+
+// AddVariation adds an alternative line that is played instead of the move at
+// the given ply. The moves can be in SAN or coordinate notation and all of them
+// must be legal.
+func (G *Game) AddVariation(atPly int, moves []string) error {
+	if atPly < 0 || atPly >= len(G.Moves) {
+		return errors.New("there is no move at ply " + strconv.Itoa(atPly) + " to add a variation to")
+	}
+	if G.variation != nil && atPly <= G.variation.Ply {
+		return errors.New("ply " + strconv.Itoa(atPly) + " is not part of the variation")
+	}
+	if len(moves) == 0 {
+		return errors.New("a variation needs at least one move")
+	}
+	p := G.positionAt(atPly)
+	v := &Variation{Ply: atPly}
+	for i, s := range moves {
+		m, err := p.ParseLegalMove(s)
+		if err != nil {
+			return errors.New("move " + strconv.Itoa(i) + " of the variation: " + err.Error())
+		}
+		p.MakeMove(m)
+		v.Moves = append(v.Moves, m)
+	}
+	if G.variation != nil {
+		// keep the tree and the game in sync:
+		G.variation.Variations = append(G.variation.Variations, v)
+		G.Variations = G.variation.Variations
+		return nil
+	}
+	G.Variations = append(G.Variations, v)
+	return nil
+}
+
+// Attach adds an existing variation as a sub-variation of v. It has to branch
+// off of one of v's moves after the first. An error is returned if v is
+// already part of the variation, since attaching it would make the tree loop
+// back on itself, or if the tree would be nested deeper than
+// MaxVariationDepth.
+func (v *Variation) Attach(sub *Variation) error {
+	if sub == nil {
+		return errors.New("can not attach a nil variation")
+	}
+	if sub.Ply <= v.Ply || sub.Ply >= v.Ply+len(v.Moves) {
+		return errors.New("variation at ply " + strconv.Itoa(sub.Ply) + " does not branch off of the variation")
+	}
+	if sub.reaches(v, 1) {
+		return errors.New("attaching the variation would create a cycle or nest too deeply")
+	}
+	v.Variations = append(v.Variations, sub)
+	return nil
+}
+
+// reaches returns whether or not the target is v or one of its
+// sub-variations. Going past MaxVariationDepth also counts as reaching it.
+func (v *Variation) reaches(target *Variation, depth int) bool {
+	if v == target || depth >= MaxVariationDepth {
+		return true
+	}
+	for _, sub := range v.Variations {
+		if sub.reaches(target, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnterVariation returns a game that follows the i'th variation instead of
+// the line it branches off of. Variations added to the returned game are
+// nested under that variation. Moves made on the returned game are not added
+// to the variation.
+func (G *Game) EnterVariation(i int) (*Game, error) {
+	if i < 0 || i >= len(G.Variations) {
+		return nil, errors.New("there is no variation " + strconv.Itoa(i))
+	}
+	v := G.Variations[i]
+	line := &Game{
+		Tags:          make(map[string]string),
+		tagOrder:      append([]string(nil), G.tagOrder...),
+		control:       G.control,
+		Position:      G.positionAt(0),
+		positionCache: make(map[uint64]int),
+		Variations:    v.Variations,
+		parent:        G,
+		variation:     v,
+	}
+	for k, t := range G.Tags {
+		line.Tags[k] = t
+	}
+	for i, m := range G.Moves[:v.Ply] {
+		if G.rejected[i] {
+			line.reject(m)
+		} else {
+			line.QuickMove(m)
+		}
+		if s, ok := G.Eval(i); ok {
+			line.SetEval(i, s)
+		}
+		if left, ok := G.MoveClock(i); ok {
+			line.SetMoveClock(i, left)
+		}
+	}
+	for _, m := range v.Moves {
+		line.QuickMove(m)
+	}
+	return line, nil
+}
+
+// ExitVariation returns the game that the variation was entered from, or nil
+// if the game is not a variation.
+func (G *Game) ExitVariation() *Game {
+	return G.parent
+}
+
+// positionAt returns a copy of the position before the move at the given
+// ply was made.
+func (G *Game) positionAt(ply int) *position.Position {
+	return G.replay(ply, nil)
+}
+
+// replay plays the first upTo moves of the game on a copy of the starting
+// position and returns the resulting position. Moves that MakeMove rejected
+// are skipped. If fn is not nil it is called for each move that is played
+// with its ply and the positions before and after it; after is the position
+// being replayed, so fn must copy it to keep it.
+func (G *Game) replay(upTo int, fn func(ply int, m move.Move, before, after *position.Position)) *position.Position {
+	if G.start == nil {
+		return position.Copy(G.Position)
+	}
+	p := position.Copy(G.start)
+	for ply, m := range G.Moves[:upTo] {
+		if G.rejected[ply] {
+			continue
+		}
+		if fn == nil {
+			p.MakeMove(m)
+			continue
+		}
+		before := position.Copy(p)
+		p.MakeMove(m)
+		fn(ply, m, before, p)
+	}
+	return p
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"github.com/reecer/chess/polyglot"
+	"github.com/reecer/chess/position/move"
+	"testing"
+)
+
+func TestAddVariation(t *testing.T) {
+	g := New()
+	for _, m := range []string{"e2e4", "e7e5", "g1f3"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if err := g.AddVariation(1, []string{"c5", "Nf3"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Variations) != 1 || g.Variations[0].Moves[0] != move.Parse("c7c5") {
+		t.Error(g.Variations)
+	}
+	if err := g.AddVariation(1, []string{"c5", "Nf6"}); err == nil {
+		t.Error("Nf6 is not a white move")
+	}
+	if err := g.AddVariation(3, []string{"Nc6"}); err == nil {
+		t.Error("there is no move at ply 3")
+	}
+	if len(g.Variations) != 1 {
+		t.Error("failed variations should not be added")
+	}
+}
+
+func TestEnterVariation(t *testing.T) {
+	g := New()
+	for _, m := range []string{"e2e4", "e7e5", "g1f3"} {
+		g.MakeMove(move.Parse(m))
+	}
+	g.AddVariation(1, []string{"c5", "Nf3"})
+	line, err := g.EnterVariation(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(line.Moves) != 3 || line.Moves[1] != move.Parse("c7c5") || line.Position.ActiveColor != g.Position.ActiveColor {
+		t.Error(line.Moves)
+	}
+	if err := line.AddVariation(0, []string{"d4"}); err == nil {
+		t.Error("ply 0 is before the variation")
+	}
+	if err := line.AddVariation(2, []string{"c3"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Variations[0].Variations) != 1 {
+		t.Error("nested variation was not added to the variation")
+	}
+	if line.ExitVariation() != g || g.ExitVariation() != nil {
+		t.Error("ExitVariation should return the parent game")
+	}
+	if _, err := g.EnterVariation(1); err == nil {
+		t.Error("there is only one variation")
+	}
+}
+
+func TestAttachVariationCycle(t *testing.T) {
+	g := New()
+	for _, m := range []string{"e2e4", "e7e5", "g1f3"} {
+		g.MakeMove(move.Parse(m))
+	}
+	g.AddVariation(1, []string{"c5", "Nf3", "d6"})
+	line, _ := g.EnterVariation(0)
+	line.AddVariation(2, []string{"c3"})
+	outer, inner := g.Variations[0], g.Variations[0].Variations[0]
+	inner.Moves = append(inner.Moves, move.Parse("d7d5"))
+	if err := inner.Attach(outer); err == nil {
+		t.Error("attaching a variation to its own sub-variation should fail")
+	}
+	if err := outer.Attach(outer); err == nil {
+		t.Error("a variation can not be attached to itself")
+	}
+	if err := outer.Attach(&Variation{Ply: 0, Moves: []move.Move{move.Parse("d2d4")}}); err == nil {
+		t.Error("ply 0 is before the variation")
+	}
+	if err := outer.Attach(&Variation{Ply: 2, Moves: []move.Move{move.Parse("b1c3")}}); err != nil {
+		t.Error(err)
+	}
+	if len(outer.Variations) != 2 || len(line.Variations) != 1 {
+		t.Error(outer.Variations, line.Variations)
+	}
+}
+
+func TestPositionAtRejectedMove(t *testing.T) {
+	g := New()
+	g.MakeMove(move.Parse("e2e5"))
+	if g.start == nil {
+		t.Fatal("an illegal first move should still record the starting position")
+	}
+	for _, m := range []string{"e2e4", "e2e5", "e7e5"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if p := g.positionAt(len(g.Moves)); polyglot.Encode(p) != polyglot.Encode(g.Position) {
+		t.Error("rejected moves should not be replayed")
+	}
+	if err := g.AddVariation(3, []string{"c5"}); err != nil {
+		t.Fatal(err)
+	}
+	line, err := g.EnterVariation(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(line.Moves) != 4 || line.Position.ActiveColor != g.Position.ActiveColor {
+		t.Error(line.Moves)
+	}
+}
@@ -0,0 +1,140 @@
+package pgn
+
+import (
+	"errors"
+	"fmt"
+	"github.com/reecer/chess/game"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotation is the commentary attached to a move. Comment holds the raw text
+// found between the braces. If the comment contained a clock ([%clk 0:05:03])
+// or an evaluation ([%eval 0.35]) command, they are also parsed into Clock and
+// Eval. NAGs are the Numeric Annotation Glyphs of the move, written as $1 or
+// as a suffix like "!" after the move.
+type Annotation struct {
+	Comment  string
+	Clock    time.Duration
+	HasClock bool
+	Eval     Score
+	HasEval  bool
+	NAGs     []int
+}
+
+// Score is an engine evaluation from white's point of view.
+type Score = game.Score
+
+var (
+	clockCommand = regexp.MustCompile(`\[%clk\s+([^\]]*)\]`)
+	evalCommand  = regexp.MustCompile(`\[%eval\s+([^\]]*)\]`)
+)
+
+// ParseScore reads a score written in pawns (0.35) or as a mate (#-2).
+// Anything after a comma is ignored since some tools append the search
+// depth: 0.35,22
+func ParseScore(s string) (Score, error) {
+	return game.ParseScore(s)
+}
+
+// ParseClock reads the time left on a clock written as h:mm:ss with optional
+// fractions of a second. ex: 0:05:03 or 1:30:00.5
+func ParseClock(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, errors.New("pgn: could not parse clock '" + s + "'")
+	}
+	var d time.Duration
+	units := []time.Duration{time.Second, time.Minute, time.Hour}
+	for i := range parts {
+		v, err := strconv.ParseFloat(parts[len(parts)-1-i], 64)
+		if err != nil || v < 0 {
+			return 0, errors.New("pgn: could not parse clock '" + s + "'")
+		}
+		d += time.Duration(v * float64(units[i]))
+	}
+	return d, nil
+}
+
+func formatClock(d time.Duration) string {
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	clock := fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	if tenths := (d % time.Second) / (100 * time.Millisecond); tenths != 0 {
+		clock += fmt.Sprint(".", int64(tenths))
+	}
+	return clock
+}
+
+// annotate adds the comment to the move at index i.
+func (p *PGN) annotate(i int, comment string) {
+	comment = strings.TrimSpace(comment)
+	if i < 0 || comment == "" {
+		return
+	}
+	a := p.Annotations[i]
+	if a.Comment != "" {
+		a.Comment += " "
+	}
+	a.Comment += comment
+	if m := clockCommand.FindStringSubmatch(comment); m != nil {
+		if clock, err := ParseClock(m[1]); err == nil {
+			a.Clock, a.HasClock = clock, true
+		}
+	}
+	if m := evalCommand.FindStringSubmatch(comment); m != nil {
+		if eval, err := ParseScore(m[1]); err == nil {
+			a.Eval, a.HasEval = eval, true
+		}
+	}
+	p.Annotations[i] = a
+}
+
+// suffixNAGs are the move suffixes that stand for a NAG.
+var suffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// addNAG adds the glyph to the move at index i. It takes either a NAG ($1) or
+// a move suffix (!).
+func (p *PGN) addNAG(i int, glyph string) {
+	nag, ok := suffixNAGs[glyph]
+	if !ok {
+		n, err := strconv.Atoi(strings.TrimPrefix(glyph, "$"))
+		if err != nil || n < 0 || n > 255 {
+			return
+		}
+		nag = n
+	}
+	if i < 0 {
+		return
+	}
+	a := p.Annotations[i]
+	a.NAGs = append(a.NAGs, nag)
+	p.Annotations[i] = a
+}
+
+// String returns the annotation as it is written inside of a PGN comment.
+// The clock and eval commands are written from Clock and Eval so that changes
+// to them are not lost. Any other text from the original comment follows.
+func (a Annotation) String() string {
+	var parts []string
+	if a.HasClock {
+		parts = append(parts, "[%clk "+formatClock(a.Clock)+"]")
+	}
+	if a.HasEval {
+		parts = append(parts, "[%eval "+a.Eval.String()+"]")
+	}
+	if text := a.text(); text != "" {
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// text returns the comment without the clock and eval commands.
+func (a Annotation) text() string {
+	text := clockCommand.ReplaceAllString(a.Comment, "")
+	text = evalCommand.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(text), " ")
+}
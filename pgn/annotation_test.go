@@ -0,0 +1,184 @@
+package pgn
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseClockComment(t *testing.T) {
+	input := `[Event "clocks"]
+[Result "*"]
+
+1. e4 {[%clk 0:01:00]} 1... e5 {[%clk 0:00:58]} 2. Nf3 {[%clk 0:00:55.5]} *
+`
+	pgn, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Duration{time.Minute, 58 * time.Second, 55*time.Second + 500*time.Millisecond}
+	for i, clock := range expected {
+		a, ok := pgn.Annotations[i]
+		if !ok || !a.HasClock || a.Clock != clock {
+			t.Error("move", i, "wanted clock", clock, "but got", a)
+		}
+	}
+	if len(pgn.Moves) != 3 || pgn.Moves[1] != "e5" {
+		t.Error(pgn.Moves)
+	}
+}
+
+func TestParseEvalComment(t *testing.T) {
+	pgn, err := Parse("1. e4 { [%eval 0.35] good move } e5 {[%eval #-3]} *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := pgn.Annotations[0]
+	if !a.HasEval || a.Eval.Centipawns != 35 || a.Comment != "[%eval 0.35] good move" {
+		t.Error(a)
+	}
+	if a := pgn.Annotations[1]; !a.HasEval || a.Eval.Mate != -3 {
+		t.Error(a)
+	}
+}
+
+func TestWriteAnnotations(t *testing.T) {
+	input := "1. e4 {[%eval 0.35] [%clk 0:05:03] book} e5 {[%clk 0:05:01]} *"
+	pgn, _ := Parse(input)
+	a := pgn.Annotations[1]
+	a.Clock = 4 * time.Minute
+	pgn.Annotations[1] = a
+	got := pgn.String()
+	if !strings.Contains(got, "1. e4 {[%clk 0:05:03] [%eval 0.35] book} e5 {[%clk 0:04:00]} ") {
+		t.Error(got)
+	}
+	reread, _ := Parse(got)
+	if reread.Annotations[0].Clock != 5*time.Minute+3*time.Second || reread.Annotations[0].Eval.Centipawns != 35 {
+		t.Error(reread.Annotations)
+	}
+}
+
+func TestScoreString(t *testing.T) {
+	scores := map[Score]string{
+		{Centipawns: 35}:   "0.35",
+		{Centipawns: -120}: "-1.20",
+		{Mate: 3}:          "#3",
+		{Mate: -2}:         "#-2",
+	}
+	for s, expected := range scores {
+		if s.String() != expected {
+			t.Error(s.String(), "!=", expected)
+		}
+		if parsed, err := ParseScore(expected); err != nil || parsed != s {
+			t.Error(expected, "parsed to", parsed, err)
+		}
+	}
+}
+
+func TestPositionsWithEval(t *testing.T) {
+	pgn, _ := Parse("1. e4 {[%eval 0.35]} e5 2. Nf3 {[%eval #4]} *")
+	g, err := Decode(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := g.PositionsWithEval()
+	if len(positions) != 4 {
+		t.Fatal("wanted 4 positions but got", len(positions))
+	}
+	if positions[0].HasEval || positions[2].HasEval {
+		t.Error("only the positions after e4 and Nf3 are evaluated")
+	}
+	if !positions[1].HasEval || positions[1].Eval.Centipawns != 35 || positions[1].Pos.ActiveColor != piece.Black {
+		t.Error("the position after e4 should be 0.35 but got", positions[1].Eval)
+	}
+	if !positions[3].HasEval || positions[3].Eval.Mate != 4 || positions[3].Pos.OnSquare(square.F3).Type != piece.Knight {
+		t.Error("the position after Nf3 should be #4 but got", positions[3].Eval)
+	}
+}
+
+func TestParseNAGs(t *testing.T) {
+	pgn, err := Parse("1. e4 $1 e5 2. Qh5?! {risky} Nc6 $2 $18 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pgn.Moves) != 4 || pgn.Moves[2] != "Qh5" {
+		t.Fatal(pgn.Moves)
+	}
+	expected := map[int][]int{0: {1}, 2: {6}, 3: {2, 18}}
+	for i, nags := range expected {
+		got := pgn.Annotations[i].NAGs
+		if len(got) != len(nags) {
+			t.Error("move", i, "wanted", nags, "but got", got)
+			continue
+		}
+		for j := range nags {
+			if got[j] != nags[j] {
+				t.Error("move", i, "wanted", nags, "but got", got)
+			}
+		}
+	}
+	if pgn.Annotations[2].Comment != "risky" {
+		t.Error(pgn.Annotations[2])
+	}
+}
+
+func TestMoveAnnotationRoundTrip(t *testing.T) {
+	g, err := Decode(&PGN{Tags: map[string]string{}, Moves: []string{"e4", "e5", "Nf3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetMoveComment(1, "the open game")
+	g.SetMoveNAG(2, []int{1, 14})
+	text := Encode(g).String()
+	if !strings.Contains(text, "e5 {the open game} 2. g1f3 $1 $14 ") {
+		t.Error(text)
+	}
+	pgn, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reread, err := Decode(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := reread.MoveComment(1); c != "the open game" {
+		t.Error("wanted the comment back but got", c)
+	}
+	if nags := reread.MoveNAG(2); len(nags) != 2 || nags[0] != 1 || nags[1] != 14 {
+		t.Error("wanted [1 14] but got", nags)
+	}
+	if reread.MoveComment(0) != "" || reread.MoveNAG(0) != nil {
+		t.Error("the first move has no annotations")
+	}
+}
+
+func TestClockRoundTrip(t *testing.T) {
+	pgn, err := Parse("1. e4 {[%clk 0:05:00]} e5 {[%clk 0:04:58.5] book} 2. Nf3 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, ok := g.MoveClock(1); !ok || left != 4*time.Minute+58500*time.Millisecond {
+		t.Error("wanted 4:58.5 left after e5 but got", left)
+	}
+	if _, ok := g.MoveClock(2); ok {
+		t.Error("Nf3 has no clock")
+	}
+	reread, err := Parse(Encode(g).String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ply, want := range map[int]time.Duration{0: 5 * time.Minute, 1: 4*time.Minute + 58500*time.Millisecond} {
+		if a := reread.Annotations[ply]; !a.HasClock || a.Clock != want {
+			t.Error("wanted the clock of ply", ply, "back as", want, "but got", a.Clock)
+		}
+	}
+	if c := reread.Annotations[1].text(); c != "book" {
+		t.Error("the comment should be kept next to the clock but got", c)
+	}
+}
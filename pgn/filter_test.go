@@ -20,9 +20,16 @@ func ExampleFilterPGNs() {
 	for _, pgn := range filtered {
 		fmt.Println(pgn)
 	}
-	// Output: [WhiteElo "3000"]
+	// Output: [Event "?"]
+	// [Site "?"]
+	// [Date "????.??.??"]
+	// [Round "?"]
+	// [White "?"]
+	// [Black "?"]
+	// [Result "*"]
+	// [WhiteElo "3000"]
 	//
-	//1. e2e4
+	// 1. e2e4 *
 	//
 }
 
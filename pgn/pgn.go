@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/reecer/chess/game"
 	"io"
+	"sort"
 	"strings"
 )
 
@@ -14,38 +15,110 @@ type PGN struct {
 	Tags         map[string]string
 	Moves        []string
 	FirstMoveNum int
+	// Annotations holds the comments of the moves keyed by their index in Moves.
+	Annotations map[int]Annotation
+	// Variations are the recursive annotation variations (RAV) of the game.
+	Variations []*Variation
+	// tagOrder is the order that the tags were read or set in.
+	tagOrder []string
+}
+
+// SetTag sets a tag of the PGN. Tags that are not part of the Seven Tag Roster
+// are written in the order that they were first set.
+func (p *PGN) SetTag(key, value string) {
+	if _, ok := p.Tags[key]; !ok {
+		p.tagOrder = append(p.tagOrder, key)
+	}
+	p.Tags[key] = value
+}
+
+// Variation is an alternative line that is written in parentheses after the
+// move that it is played instead of. Ply is the index of that move counted
+// from the first move of the game, even for variations within variations.
+type Variation struct {
+	Ply        int
+	Moves      []string
+	Variations []*Variation
 }
 
 func (p PGN) String() string {
 	s := ""
+	// The Seven Tag Roster is always written, with defaults for missing tags:
 	ordering := []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+	defaults := map[string]string{"Date": "????.??.??", "Result": "*"}
 	for _, t := range ordering {
-		if v, ok := p.Tags[t]; ok {
-			s += fmt.Sprint("[", t, " ", "\"", v, "\"]\n")
+		v, ok := p.Tags[t]
+		if !ok {
+			if v, ok = defaults[t]; !ok {
+				v = "?"
+			}
 		}
+		s += fmt.Sprint("[", t, " ", "\"", v, "\"]\n")
 	}
-	alreadyPrinted := func(t string) bool {
-		for _, v := range ordering {
-			if v == t {
-				return true
-			}
+	alreadyPrinted := map[string]bool{}
+	for _, t := range ordering {
+		alreadyPrinted[t] = true
+	}
+	// then the rest in the order they were set:
+	var extra []string
+	for _, t := range p.tagOrder {
+		if _, ok := p.Tags[t]; ok && !alreadyPrinted[t] {
+			extra = append(extra, t)
+			alreadyPrinted[t] = true
 		}
-		return false
 	}
-	for t, v := range p.Tags {
-		if !alreadyPrinted(t) {
-			s += fmt.Sprint("[", t, " ", "\"", v, "\"]\n")
+	var unordered []string
+	for t := range p.Tags {
+		if !alreadyPrinted[t] {
+			unordered = append(unordered, t)
 		}
 	}
+	sort.Strings(unordered)
+	for _, t := range append(extra, unordered...) {
+		s += fmt.Sprint("[", t, " ", "\"", p.Tags[t], "\"]\n")
+	}
 	s += fmt.Sprintln()
-	for i, m := range p.Moves {
-		if i%2 == 0 {
-			s += fmt.Sprint(p.FirstMoveNum+(i/2), ". ")
+	s += p.movetext(p.Moves, 0, p.Variations, p.Annotations, false, 0)
+	if result, ok := p.Tags["Result"]; ok {
+		s += fmt.Sprintln(result)
+	} else {
+		s += fmt.Sprintln("*")
+	}
+	s += fmt.Sprintln()
+	return s
+}
+
+// movetext writes out a line of moves that starts at the given ply along
+// with its variations. If resume is set, the number of a first move by black
+// is written out as "N...". Variations nested deeper than
+// game.MaxVariationDepth are left out.
+func (p PGN) movetext(moves []string, ply int, variations []*Variation, annotations map[int]Annotation, resume bool, depth int) string {
+	s := ""
+	for i, m := range moves {
+		n := ply + i
+		if n%2 == 0 {
+			s += fmt.Sprint(p.FirstMoveNum+(n/2), ". ")
+		} else if resume {
+			s += fmt.Sprint(p.FirstMoveNum+(n/2), "... ")
 		}
+		resume = false
 		s += fmt.Sprint(m, " ")
+		if a, ok := annotations[i]; ok {
+			for _, nag := range a.NAGs {
+				s += fmt.Sprint("$", nag, " ")
+			}
+			if c := a.String(); c != "" {
+				s += fmt.Sprint("{", c, "} ")
+			}
+		}
+		for _, v := range variations {
+			if v.Ply == n && depth < game.MaxVariationDepth {
+				s += "(" + strings.TrimSpace(p.movetext(v.Moves, v.Ply, v.Variations, nil, true, depth+1)) + ") "
+				// the move after a variation needs its number again:
+				resume = true
+			}
+		}
 	}
-	s += fmt.Sprintln(p.Tags["Result"])
-	s += fmt.Sprintln()
 	return s
 }
 
@@ -64,29 +137,96 @@ func (p *PGN) UnmarshalText(text []byte) error {
 	}
 	p.Tags = pgn.Tags
 	p.Moves = pgn.Moves
+	p.Annotations = pgn.Annotations
+	p.Variations = pgn.Variations
+	p.tagOrder = pgn.tagOrder
 	return nil
 }
 
 // Decode returns a Game from a PGN struct. To load a PGN string ParsePGN()
-// or use ReadPGN() to load it from a file.
+// or use ReadPGN() to load it from a file. An error is returned if one of
+// the moves is illegal.
 func Decode(pgn *PGN) (*game.Game, error) {
 	g := game.New()
 	g.Tags = pgn.Tags
+	for _, t := range pgn.tagOrder {
+		if v, ok := pgn.Tags[t]; ok {
+			g.SetTag(t, v)
+		}
+	}
 	for _, san := range pgn.Moves {
 		move, err := g.Position.ParseMove(san)
 		if err != nil {
 			return nil, err
 		}
-		g.MakeMove(move)
+		if g.MakeMove(move)&(game.WhiteIllegalMove|game.BlackIllegalMove) != 0 {
+			return nil, errors.New("pgn: illegal move '" + san + "'")
+		}
+	}
+	for i, a := range pgn.Annotations {
+		if a.HasEval {
+			g.SetEval(i, a.Eval)
+		}
+		if a.HasClock {
+			g.SetMoveClock(i, a.Clock)
+		}
+		g.SetMoveComment(i, a.text())
+		g.SetMoveNAG(i, a.NAGs)
+	}
+	if err := decodeVariations(g, pgn.Variations, 0); err != nil {
+		return nil, err
 	}
 	return g, nil
 }
 
+func decodeVariations(g *game.Game, variations []*Variation, depth int) error {
+	if len(variations) > 0 && depth >= game.MaxVariationDepth {
+		return errors.New("pgn: variations are nested too deeply")
+	}
+	for _, v := range variations {
+		if err := g.AddVariation(v.Ply, v.Moves); err != nil {
+			return err
+		}
+		if len(v.Variations) == 0 {
+			continue
+		}
+		line, err := g.EnterVariation(len(g.Variations) - 1)
+		if err != nil {
+			return err
+		}
+		if err := decodeVariations(line, v.Variations, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeVariations converts the variations of a game. Variations nested
+// deeper than game.MaxVariationDepth are left out. skipped returns how many
+// rejected moves come before a ply, so the variation can be moved up with the
+// main line.
+func encodeVariations(variations []*game.Variation, depth int, skipped func(ply int) int) []*Variation {
+	if depth >= game.MaxVariationDepth {
+		return nil
+	}
+	var encoded []*Variation
+	for _, v := range variations {
+		n := skipped(v.Ply)
+		e := &Variation{Ply: v.Ply - n, Variations: encodeVariations(v.Variations, depth+1, func(int) int { return n })}
+		for _, m := range v.Moves {
+			e.Moves = append(e.Moves, m.String())
+		}
+		encoded = append(encoded, e)
+	}
+	return encoded
+}
+
 // New returns a new blank PGN game.
 func New() *PGN {
 	return &PGN{
 		Tags:         make(map[string]string),
 		FirstMoveNum: 1,
+		Annotations:  make(map[int]Annotation),
 	}
 }
 
@@ -99,6 +239,7 @@ func Encode(G *game.Game) *PGN {
 	pgn := New()
 	//G.appendTags()
 	pgn.Tags = G.Tags
+	pgn.tagOrder = G.TagOrder()
 	pgn.Tags["Result"] = G.Result()
 	/*
 		firstRealMove := 0
@@ -111,11 +252,26 @@ func Encode(G *game.Game) *PGN {
 		}
 		pgn.FirstMoveNum = firstRealMove/2 + 1
 	*/
-	firstRealMove := G.Position.MoveNumber - (len(G.Moves) / 2)
+	// Moves that were rejected as illegal are left out and the annotations
+	// and variations after them are moved up.
+	played := G.PlayedMoves()
+	firstRealMove := G.Position.MoveNumber - (len(played) / 2)
 	pgn.FirstMoveNum = firstRealMove
-	for i := 0; i < len(G.Moves); i++ {
-		pgn.Moves = append(pgn.Moves, G.Moves[i].String())
+	for i, ply := range played {
+		pgn.Moves = append(pgn.Moves, G.Moves[ply].String())
+		var a Annotation
+		a.Eval, a.HasEval = G.Eval(ply)
+		a.Clock, a.HasClock = G.MoveClock(ply)
+		a.Comment = G.MoveComment(ply)
+		a.NAGs = G.MoveNAG(ply)
+		if a.HasEval || a.HasClock || a.Comment != "" || len(a.NAGs) > 0 {
+			pgn.Annotations[i] = a
+		}
+	}
+	skipped := func(ply int) int {
+		return ply - sort.SearchInts(played, ply)
 	}
+	pgn.Variations = encodeVariations(G.Variations, 0, skipped)
 	return pgn
 }
 
@@ -137,10 +293,30 @@ func Parse(pgn string) (*PGN, error) {
 // into a PGN. If you want to load a PGN fron a string you can use ParsePGN(your_pgn_str)
 func Read(file io.Reader) ([]*PGN, error) {
 	var GameList []*PGN
+	err := scan(file, func(game *PGN, movetext string) error {
+		appendMoves(game, movetext)
+		GameList = append(GameList, game)
+		return nil
+	})
+	return GameList, err
+}
+
+// Scan reads through the games in the file and calls fn with the tags and
+// the raw movetext of each one. The movetext is not parsed, so it is much
+// faster than Read when only some of the games are needed. If fn returns an
+// error, scanning stops and that error is returned.
+func Scan(file io.Reader, fn func(tags map[string]string, movetext string) error) error {
+	return scan(file, func(game *PGN, movetext string) error {
+		return fn(game.Tags, movetext)
+	})
+}
+
+func scan(file io.Reader, fn func(game *PGN, movetext string) error) error {
 	// Read line by line:
 	scanner := bufio.NewScanner(file)
 	readingmoves := false // flag
 	currentGame := New()
+	var movetext []byte
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		if len(line) == 0 {
@@ -151,70 +327,100 @@ func Read(file io.Reader) ([]*PGN, error) {
 				// since we are no longer reading moves, we know this is a new game
 				readingmoves = false
 				// so we need to sort out what to do with the game that we previously read:
-				GameList = append(GameList, currentGame)
+				if err := fn(currentGame, string(movetext)); err != nil {
+					return err
+				}
 				currentGame = New()
+				movetext = movetext[:0]
 			}
 			key, value := splitTag(line)
-			currentGame.Tags[string(key)] = string(value)
+			currentGame.SetTag(string(key), string(value))
 		} else {
 			readingmoves = true
-			appendMoves(currentGame, line)
+			// comments can span multiple lines so the movetext is parsed all at once:
+			movetext = append(append(movetext, line...), '\n')
 		}
 	}
-	GameList = append(GameList, currentGame)
-	err := scanner.Err()
-	return GameList, err
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(currentGame.Tags) > 0 || len(movetext) > 0 {
+		return fn(currentGame, string(movetext))
+	}
+	return nil
 }
 
-func appendMoves(game *PGN, line []byte) {
-	// example: 1. e2e4 d7d5 2. b1c3 f7f5 {asd asd} 3. a2a3 ;asdasdasdasd"
-	l := removeComments(line)
-	//l = RemoveNumbering(line)
-	moves := strings.Split(string(l), " ")
-	for _, m := range moves {
-		if strings.Contains(m, ".") {
-			m = strings.Split(m, ".")[1]
-			m = strings.Trim(m, " ")
-		}
-		//if isMove(StripAnnotations(m)) {
-		if m != "1/2-1/2" && m != "1-0" && m != "0-1" && m != "*" && m != "" {
-			game.Moves = append(game.Moves, m)
-		}
-		//}
-
-	}
+func appendMoves(game *PGN, movetext string) {
+	// example: 1. e2e4 d7d5 2. b1c3 (2. a2a3) f7f5 {asd asd} 3. a2a3 ;asdasdasdasd"
+	game.Moves, game.Variations, _ = readLine(game, movetext, 0, 0, true)
 }
 
-func removeComments(line []byte) []byte {
-	marker := 0
-	i := 0
-	for {
-		if line[i] == ';' {
-			if i-1 < 0 {
-				return []byte{}
+// readLine reads the moves of a line starting from index i of the movetext
+// until the line ends. ply is the ply of the first move in the line. Comments
+// are only kept for the mainline. The index after the line is returned.
+func readLine(game *PGN, movetext string, i, ply int, mainline bool) (moves []string, variations []*Variation, next int) {
+	for i < len(movetext) {
+		switch movetext[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		case '{':
+			// comments belong to the move before them:
+			end := strings.IndexByte(movetext[i:], '}')
+			if end < 0 {
+				end = len(movetext) - i
 			}
-			return line[:i-1]
-		}
-		if line[i] == '{' {
-			marker = i
-		}
-		if line[i] == '}' {
-			if marker-1 < 0 {
-				marker = marker + 1
+			if mainline {
+				game.annotate(len(moves)-1, movetext[i+1:i+end])
 			}
-			if i+1 > len(line)-1 {
-				line = line[:marker-1]
-			} else {
-				line = append(line[:marker-1], line[i+1:]...)
+			i += end + 1
+		case ';':
+			end := strings.IndexByte(movetext[i:], '\n')
+			if end < 0 {
+				end = len(movetext) - i
+			}
+			i += end
+		case '(':
+			// a variation replaces the move before it:
+			start := ply + len(moves) - 1
+			v := &Variation{Ply: start}
+			v.Moves, v.Variations, i = readLine(game, movetext, i+1, start, false)
+			if len(moves) > 0 && len(v.Moves) > 0 {
+				variations = append(variations, v)
+			}
+		case ')':
+			i++
+			if !mainline {
+				return moves, variations, i
+			}
+		default:
+			end := i
+			for end < len(movetext) && !strings.ContainsRune(" \t\r\n{;()", rune(movetext[end])) {
+				end++
+			}
+			m := movetext[i:end]
+			i = end
+			if strings.Contains(m, ".") {
+				m = m[strings.LastIndex(m, ".")+1:]
+			}
+			if strings.HasPrefix(m, "$") {
+				if mainline {
+					game.addNAG(len(moves)-1, m)
+				}
+				continue
+			}
+			glyph := ""
+			if suffix := strings.IndexAny(m, "!?"); suffix > 0 {
+				m, glyph = m[:suffix], m[suffix:]
+			}
+			if m != "1/2-1/2" && m != "1-0" && m != "0-1" && m != "*" && m != "" {
+				moves = append(moves, m)
+				if glyph != "" && mainline {
+					game.addNAG(len(moves)-1, glyph)
+				}
 			}
-			i = marker
-		}
-		i++
-		if i > len(line)-1 {
-			break
 		}
 	}
-	return line
+	return moves, variations, i
 }
 
 /*
@@ -1,6 +1,7 @@
 package pgn
 
 import (
+	"errors"
 	"fmt"
 	"github.com/reecer/chess/fen"
 	"github.com/reecer/chess/game"
@@ -46,14 +47,13 @@ func TestPGNString(t *testing.T) {
 }
 
 func TestPGNnullmoves(t *testing.T) {
-	expected := `[Result "1-0"]
-[Setup "1"]
-[FEN "rnbq1bnr/ppppkppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQ - 1 3"]
-
-3. h5e5 1-0
-
-`
-	expectedAlt := `[Result "1-0"]
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
 [FEN "rnbq1bnr/ppppkppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQ - 1 3"]
 [Setup "1"]
 
@@ -68,16 +68,21 @@ func TestPGNnullmoves(t *testing.T) {
 	}
 	g.MakeMove(m)
 	got := Encode(g).String()
-	if got != expected && got != expectedAlt {
+	if got != expected {
 		t.Log("wanted:\n", expected)
-		t.Log("or:\n", expectedAlt)
 		t.Log("got:\n", got)
 		t.Fail()
 	}
 }
 
 func TestPGNoutput(t *testing.T) {
-	expected := `[Result "1-0"]
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
 
 1. e2e4 e7e5 2. d1h5 e8e7 3. h5e5 1-0
 
@@ -204,20 +209,21 @@ func TestFromPGN(t *testing.T) {
 }
 
 func TestStripBracketComments(t *testing.T) {
-	moves := "1. e4 d5 { comment here } 2. d4 e5"
-	p := removeComments([]byte(moves))
-	if string(p) != "1. e4 d5 2. d4 e5" {
-		t.Fail()
+	p := New()
+	appendMoves(p, "1. e4 d5 { comment\nhere } 2. d4 e5")
+	if strings.Join(p.Moves, " ") != "e4 d5 d4 e5" {
+		t.Error(p.Moves)
+	}
+	if c := p.Annotations[1].Comment; c != "comment\nhere" {
+		t.Error("the comment should be kept on d5 but got", c)
 	}
 }
 
 func TestStripColonComments(t *testing.T) {
-	moves := "1. e4 d5 ; something here"
-	expected := "1. e4 d5"
-	p := removeComments([]byte(moves))
-	if string(p) != expected {
-		t.Log(string(p))
-		t.Fail()
+	p := New()
+	appendMoves(p, "1. e4 d5 ; something here 2. d4\n2. c4 e6")
+	if strings.Join(p.Moves, " ") != "e4 d5 c4 e6" {
+		t.Error("the rest of the line should be skipped but got", p.Moves)
 	}
 }
 
@@ -255,3 +261,161 @@ func TestStatusStringInDraw(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestWriteVariations(t *testing.T) {
+	g := game.New()
+	for _, m := range []string{"e2e4", "e7e5", "g1f3"} {
+		g.MakeMove(move.Parse(m))
+	}
+	if err := g.AddVariation(1, []string{"c5", "Nf3", "d6"}); err != nil {
+		t.Fatal(err)
+	}
+	line, _ := g.EnterVariation(0)
+	if err := line.AddVariation(2, []string{"c3"}); err != nil {
+		t.Fatal(err)
+	}
+	got := Encode(g).String()
+	expected := "1. e2e4 e7e5 (1... c7c5 2. g1f3 (2. c2c3) 2... d7d6) 2. g1f3 *"
+	if !strings.Contains(got, expected) {
+		t.Error("wanted", expected, "in", got)
+	}
+	reread, err := Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reread.Moves) != 3 || len(reread.Variations) != 1 || len(reread.Variations[0].Variations) != 1 {
+		t.Fatal(reread.Moves, reread.Variations)
+	}
+	if v := reread.Variations[0].Variations[0]; v.Ply != 2 || v.Moves[0] != "c2c3" {
+		t.Error(v)
+	}
+	decoded, err := Decode(reread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Variations) != 1 || len(decoded.Variations[0].Variations) != 1 {
+		t.Error(decoded.Variations)
+	}
+}
+
+func TestEncodeRejectedMove(t *testing.T) {
+	g := game.New()
+	for _, m := range []string{"e2e4", "e2e5", "e7e5", "g1f3"} {
+		g.MakeMove(move.Parse(m))
+	}
+	g.SetMoveComment(2, "the open game")
+	if err := g.AddVariation(3, []string{"Nc3"}); err != nil {
+		t.Fatal(err)
+	}
+	p := Encode(g)
+	if len(p.Moves) != 3 || p.Moves[1] != "e7e5" || p.FirstMoveNum != 1 {
+		t.Fatal("the rejected move should be left out but got", p.Moves, p.FirstMoveNum)
+	}
+	if p.Annotations[1].Comment != "the open game" {
+		t.Error("the comment on e5 should move up with it but got", p.Annotations)
+	}
+	if len(p.Variations) != 1 || p.Variations[0].Ply != 2 {
+		t.Error("the variation on Nf3 should move up with it but got", p.Variations)
+	}
+}
+
+func TestDecodeIllegalMove(t *testing.T) {
+	pgn := New()
+	pgn.Moves = []string{"e2e4", "e7e5", "e4e5"}
+	if _, err := Decode(pgn); err == nil {
+		t.Error("e4e5 is blocked and should not decode")
+	}
+	g := game.New()
+	for _, m := range []string{"e2e4", "e2e5", "e7e5"} {
+		g.MakeMove(move.Parse(m))
+	}
+	reread, err := Decode(Encode(g))
+	if err != nil {
+		t.Fatal("a game with a rejected move should still round trip but got", err)
+	}
+	if len(reread.Moves) != 2 || reread.Moves[1] != move.Parse("e7e5") {
+		t.Error(reread.Moves)
+	}
+}
+
+func TestSevenTagRoster(t *testing.T) {
+	g := game.New()
+	got := Encode(g).String()
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+*
+
+`
+	if got != expected {
+		t.Error("wanted:\n", expected, "\ngot:\n", got)
+	}
+}
+
+func TestTagOrder(t *testing.T) {
+	g := game.New()
+	g.SetTag("White", "Carlsen")
+	g.SetTag("WhiteElo", "2850")
+	g.SetTag("Annotator", "me")
+	g.SetTag("ECO", "C20")
+	got := Encode(g).String()
+	expected := `[White "Carlsen"]
+[Black "?"]
+[Result "*"]
+[WhiteElo "2850"]
+[Annotator "me"]
+[ECO "C20"]
+`
+	if !strings.Contains(got, expected) {
+		t.Error("wanted:\n", expected, "\nin:\n", got)
+	}
+	reread, _ := Parse(got)
+	if again := reread.String(); again != got {
+		t.Error("tag order should survive reading the PGN back:\n", again)
+	}
+}
+
+func TestScan(t *testing.T) {
+	input := `[Event "one"]
+[White "a"]
+
+1. e4 e5 *
+
+[Event "two"]
+
+1. d4 d5 {comment} *
+
+[Event "three"]
+
+1. c4 *
+`
+	var events, movetext []string
+	err := Scan(strings.NewReader(input), func(tags map[string]string, m string) error {
+		events = append(events, tags["Event"])
+		movetext = append(movetext, m)
+		return nil
+	})
+	if err != nil || len(events) != 3 || events[2] != "three" {
+		t.Fatal(events, err)
+	}
+	if movetext[1] != "1. d4 d5 {comment} *\n" {
+		t.Errorf("movetext should not be parsed: %q", movetext[1])
+	}
+	stop := errors.New("stop")
+	calls := 0
+	err = Scan(strings.NewReader(input), func(tags map[string]string, m string) error {
+		calls++
+		if tags["Event"] == "two" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 2 {
+		t.Error("scanning should stop at the second game", calls, err)
+	}
+}
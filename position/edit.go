@@ -0,0 +1,68 @@
+package position
+
+import (
+	"errors"
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+)
+
+// SetSideToMove sets whose turn it is. The en passant square is cleared since
+// it only ever belongs to the player that is about to move.
+func (p *Position) SetSideToMove(c piece.Color) {
+	if c != p.ActiveColor {
+		p.EnPassant = square.NoSquare
+	}
+	p.ActiveColor = c
+}
+
+// SetCastlingRight gives or takes away the right to castle on the given side
+// (ShortSide or LongSide). A right can only be given if the king and the rook
+// are still on their starting squares.
+func (p *Position) SetCastlingRight(c piece.Color, side uint, allowed bool) error {
+	if allowed {
+		king := [2]square.Square{square.E1, square.E8}[c]
+		rook := [2][2]square.Square{{square.H1, square.A1}, {square.H8, square.A8}}[c][side]
+		if p.OnSquare(king) != piece.New(c, piece.King) {
+			return errors.New("can not castle without a king on " + king.String())
+		}
+		if p.OnSquare(rook) != piece.New(c, piece.Rook) {
+			return errors.New("can not castle without a rook on " + rook.String())
+		}
+	}
+	p.CastlingRights[c][side] = allowed
+	return nil
+}
+
+// SetEnPassant sets the en passant square, or clears it when ok is false.
+// The square has to be one that the opponent's pawn just skipped over with a
+// double push: it and the pawn's starting square must be empty and the pawn
+// must be on the square in front of it. Since a position does not know the
+// move that led to it, positions set up with Put never get an en passant
+// square on their own. See SetEnPassantFromLastDoublePush in the game package.
+func (p *Position) SetEnPassant(sq square.Square, ok bool) error {
+	if !ok {
+		p.EnPassant = square.NoSquare
+		return nil
+	}
+	if sq > square.LastSquare {
+		return errors.New("invalid en passant square")
+	}
+	opponent := []piece.Color{piece.Black, piece.White}[p.ActiveColor]
+	rank := []int{6, 3}[p.ActiveColor]
+	if int(sq)/8+1 != rank {
+		return errors.New("en passant square " + sq.String() + " is not on the right rank")
+	}
+	// The pawn moved away from us, so its starting square is behind the
+	// en passant square and it is now in front:
+	direction := []int{-8, 8}[p.ActiveColor]
+	pawn := square.Square(int(sq) + direction)
+	start := square.Square(int(sq) - direction)
+	if p.OnSquare(pawn) != piece.New(opponent, piece.Pawn) {
+		return errors.New("there is no pawn to capture en passant on " + sq.String())
+	}
+	if p.OnSquare(sq).Type != piece.None || p.OnSquare(start).Type != piece.None {
+		return errors.New("a pawn could not have just skipped over " + sq.String())
+	}
+	p.EnPassant = sq
+	return nil
+}
@@ -0,0 +1,56 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestSetSideToMove(t *testing.T) {
+	b := New()
+	b.EnPassant = square.E3
+	b.SetSideToMove(piece.Black)
+	if b.ActiveColor != piece.Black || b.EnPassant != square.NoSquare {
+		t.Fail()
+	}
+}
+
+func TestSetCastlingRight(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.H1)
+	if err := b.SetCastlingRight(piece.White, ShortSide, true); err != nil || !b.CastlingRights[piece.White][ShortSide] {
+		t.Error(err)
+	}
+	if err := b.SetCastlingRight(piece.White, LongSide, true); err == nil {
+		t.Error("there is no rook on a1")
+	}
+	if err := b.SetCastlingRight(piece.Black, ShortSide, true); err == nil {
+		t.Error("there is no king on e8")
+	}
+	if err := b.SetCastlingRight(piece.Black, LongSide, false); err != nil || b.CastlingRights[piece.Black][LongSide] {
+		t.Error("rights can always be taken away")
+	}
+}
+
+func TestSetEnPassant(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E5)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D5)
+	if err := b.SetEnPassant(square.D6, true); err != nil || b.EnPassant != square.D6 {
+		t.Error(err)
+	}
+	if err := b.SetEnPassant(square.F6, true); err == nil {
+		t.Error("there is no pawn on f5")
+	}
+	if err := b.SetEnPassant(square.D3, true); err == nil {
+		t.Error("d3 is on the wrong rank for white to move")
+	}
+	if err := b.SetEnPassant(square.D6, false); err != nil || b.EnPassant != square.NoSquare {
+		t.Error("en passant should be cleared")
+	}
+}
@@ -0,0 +1,167 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+)
+
+// Phase weights of the pieces. Pawns and kings do not count towards the
+// phase of the game.
+const (
+	KnightPhase = 1
+	BishopPhase = 1
+	RookPhase   = 2
+	QueenPhase  = 4
+	// MaxPhase is the phase of the opening position:
+	//   4 knights + 4 bishops + 4 rooks * 2 + 2 queens * 4 = 24
+	MaxPhase = 24
+)
+
+// GamePhase returns how far the game is from being an endgame based on the
+// non-pawn material left on the board. MaxPhase (24) is a full middlegame and
+// 0 is a pure pawn (or bare kings) endgame. Extra material from promotions
+// can not push the phase past MaxPhase. It is meant for tapered evaluation:
+//
+//	score = (middlegame*phase + endgame*(MaxPhase-phase)) / MaxPhase
+func (p *Position) GamePhase() int {
+	weights := map[piece.Type]int{
+		piece.Knight: KnightPhase,
+		piece.Bishop: BishopPhase,
+		piece.Rook:   RookPhase,
+		piece.Queen:  QueenPhase,
+	}
+	phase := 0
+	for c := piece.White; c <= piece.Black; c++ {
+		for pc, weight := range weights {
+			phase += int(popcount(p.bitBoard[c][pc])) * weight
+		}
+	}
+	if phase > MaxPhase {
+		return MaxPhase
+	}
+	return phase
+}
+
+// PieceCounts returns how many of each type of piece are on the board.
+// The counts are indexed by color: [piece.White, piece.Black].
+func (p *Position) PieceCounts() map[piece.Type][2]int {
+	counts := make(map[piece.Type][2]int)
+	for pc := piece.Pawn; pc <= piece.King; pc++ {
+		counts[pc] = [2]int{
+			int(popcount(p.bitBoard[piece.White][pc])),
+			int(popcount(p.bitBoard[piece.Black][pc])),
+		}
+	}
+	return counts
+}
+
+// BishopPair returns whether or not the color has two or more bishops.
+func (p *Position) BishopPair(c piece.Color) bool {
+	return popcount(p.bitBoard[c][piece.Bishop]) >= 2
+}
+
+// HasBishopPair returns whether or not the color has bishops on both colors of
+// squares. Unlike BishopPair, two bishops on the same color (which can only
+// happen through underpromotion) do not count as a pair.
+func (p *Position) HasBishopPair(c piece.Color) bool {
+	var light, dark bool
+	bishops := p.bitBoard[c][piece.Bishop]
+	for bishops != 0 {
+		sq := bitscan(bishops)
+		if squareColor(sq) == piece.White {
+			light = true
+		} else {
+			dark = true
+		}
+		bishops ^= (1 << sq)
+	}
+	return light && dark
+}
+
+// squareColor returns the color of the square at the given index.
+func squareColor(sq uint) piece.Color {
+	// a1 (index 7) is a dark square:
+	if (sq/8+sq%8)%2 == 1 {
+		return piece.Black
+	}
+	return piece.White
+}
+
+// pieceValues are the usual centipawn values of the pieces. The king is given
+// a value larger than all of the other pieces put together.
+var pieceValues = map[piece.Type]int{
+	piece.None:   0,
+	piece.Pawn:   100,
+	piece.Knight: 300,
+	piece.Bishop: 300,
+	piece.Rook:   500,
+	piece.Queen:  900,
+	piece.King:   10000,
+}
+
+// IsTheoreticalDraw returns whether or not the position is a draw that no
+// amount of good play can win. It only returns true when it is certain, which
+// covers:
+//
+//   - neither side having enough material to checkmate (InsufficientMaterial)
+//   - a king, pawns on a single rook file and any bishops that do not cover
+//     the promotion square against a lone king that is on or next to the
+//     promotion square (the rook pawn and the wrong bishop)
+//
+// Endgames that are usually drawn but need a search to be sure of, such as a
+// rook against a rook or a queen against a pawn on the seventh rank, are not
+// recognized.
+func (p *Position) IsTheoreticalDraw() bool {
+	if p.InsufficientMaterial() {
+		return true
+	}
+	return p.wrongRookPawn(piece.White) || p.wrongRookPawn(piece.Black)
+}
+
+// wrongRookPawn returns whether or not the color only has rook pawns on one
+// file and bishops that can not help them promote against a lone king that
+// already stands in front of them.
+func (p *Position) wrongRookPawn(c piece.Color) bool {
+	defender := []piece.Color{piece.Black, piece.White}[c]
+	if p.occupied(defender) != p.bitBoard[defender][piece.King] {
+		return false
+	}
+	pawns := p.bitBoard[c][piece.Pawn]
+	bishops := p.bitBoard[c][piece.Bishop]
+	if pawns == 0 || p.occupied(c) != pawns|bishops|p.bitBoard[c][piece.King] {
+		return false
+	}
+	file := bitscan(pawns) % 8
+	if file != 0 && file != 7 {
+		return false
+	}
+	for pawns != 0 {
+		sq := bitscan(pawns)
+		if sq%8 != file {
+			return false
+		}
+		pawns ^= (1 << sq)
+	}
+	promotion := []uint{56, 0}[c] + file
+	for bishops != 0 {
+		sq := bitscan(bishops)
+		if squareColor(sq) == squareColor(promotion) {
+			return false
+		}
+		bishops ^= (1 << sq)
+	}
+	return squareDistance(bitscan(p.bitBoard[defender][piece.King]), promotion) <= 1
+}
+
+// TempoBonus is the bonus in centipawns for having the move. Values of 10 to
+// 20 are typical.
+var TempoBonus = 15
+
+// Tempo returns the bonus for having the move from white's point of view: it
+// is TempoBonus when white is to move and -TempoBonus when black is. Adding it
+// to an evaluation keeps the scores of odd and even search depths closer.
+func (p *Position) Tempo() int {
+	if p.ActiveColor == piece.Black {
+		return -TempoBonus
+	}
+	return TempoBonus
+}
@@ -0,0 +1,139 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestGamePhaseStart(t *testing.T) {
+	if phase := New().GamePhase(); phase != MaxPhase {
+		t.Error("start position should be a full middlegame but got", phase)
+	}
+}
+
+func TestGamePhaseBareKings(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E4)
+	if phase := b.GamePhase(); phase != 0 {
+		t.Error("pawn endgame should have phase 0 but got", phase)
+	}
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.A8)
+	if phase := b.GamePhase(); phase != RookPhase {
+		t.Error("wanted", RookPhase, "but got", phase)
+	}
+}
+
+func TestGamePhasePromotions(t *testing.T) {
+	b := New()
+	b.QuickPut(piece.New(piece.White, piece.Queen), square.E4)
+	if phase := b.GamePhase(); phase != MaxPhase {
+		t.Error("phase should not go past", MaxPhase, "but got", phase)
+	}
+}
+
+func TestPieceCountsStart(t *testing.T) {
+	counts := New().PieceCounts()
+	expected := map[piece.Type][2]int{
+		piece.Pawn: {8, 8}, piece.Knight: {2, 2}, piece.Bishop: {2, 2},
+		piece.Rook: {2, 2}, piece.Queen: {1, 1}, piece.King: {1, 1},
+	}
+	for pc, count := range expected {
+		if counts[pc] != count {
+			t.Error(pc, "wanted", count, "but got", counts[pc])
+		}
+	}
+}
+
+func TestPieceCountsAfterTrades(t *testing.T) {
+	b := New()
+	// 1. e4 d5 2. exd5 Qxd5 3. Nc3 Qxa2 4. Rxa2
+	for _, m := range []string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3", "d5a2", "a1a2"} {
+		b.MakeMove(move.Parse(m))
+	}
+	counts := b.PieceCounts()
+	if counts[piece.Pawn] != [2]int{6, 7} || counts[piece.Queen] != [2]int{1, 0} || counts[piece.Rook] != [2]int{2, 2} {
+		t.Error(counts)
+	}
+	if !b.BishopPair(piece.White) || !b.BishopPair(piece.Black) {
+		t.Error("both sides still have their bishops")
+	}
+	b.ClearSquare(square.C8)
+	if b.BishopPair(piece.Black) {
+		t.Error("black only has one bishop")
+	}
+}
+
+func TestHasBishopPair(t *testing.T) {
+	b := New()
+	if !b.HasBishopPair(piece.White) || !b.HasBishopPair(piece.Black) {
+		t.Error("both sides start with the bishop pair")
+	}
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.C1)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.A3)
+	if b.HasBishopPair(piece.White) || !b.BishopPair(piece.White) {
+		t.Error("c1 and a3 are both dark squares")
+	}
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.F1)
+	if !b.HasBishopPair(piece.White) {
+		t.Error("f1 is a light square")
+	}
+}
+
+func TestIsTheoreticalDraw(t *testing.T) {
+	if New().IsTheoreticalDraw() {
+		t.Error("the start position is not a draw")
+	}
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E5)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.C1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.A5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.B8)
+	if !b.IsTheoreticalDraw() {
+		t.Error("the dark squared bishop can not drive the king out of a8")
+	}
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.F1)
+	if b.IsTheoreticalDraw() {
+		t.Error("the light squared bishop covers a8")
+	}
+	b.ClearSquare(square.F1)
+	b.ClearSquare(square.B8)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	if b.IsTheoreticalDraw() {
+		t.Error("the king is too far away to stop the pawn")
+	}
+	b.Clear()
+	b.QuickPut(piece.New(piece.Black, piece.King), square.A4)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.H4)
+	b.QuickPut(piece.New(piece.White, piece.King), square.G1)
+	if !b.IsTheoreticalDraw() {
+		t.Error("the white king is in front of the black h pawn")
+	}
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.G5)
+	if b.IsTheoreticalDraw() {
+		t.Error("the g pawn is not a rook pawn")
+	}
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	if !b.IsTheoreticalDraw() {
+		t.Error("bare kings are a draw")
+	}
+}
+
+func TestTempo(t *testing.T) {
+	b := New()
+	if tempo := b.Tempo(); tempo != TempoBonus || tempo <= 0 {
+		t.Error("white to move should be ahead but got", tempo)
+	}
+	b.SetSideToMove(piece.Black)
+	if tempo := b.Tempo(); tempo != -TempoBonus {
+		t.Error("black to move should be ahead but got", tempo)
+	}
+}
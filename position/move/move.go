@@ -4,6 +4,7 @@ package move
 import (
 	"github.com/reecer/chess/piece"
 	"github.com/reecer/chess/position/square"
+	"strings"
 	"time"
 )
 
@@ -20,9 +21,38 @@ var (
 	Null = Move{square.NoSquare, square.NoSquare, piece.None, 0}
 )
 
-// Parse takes a move in PCN format and return a Move struct.
+// Flag describes what a move does in a position. Moves do not carry their
+// flags since they are compared and used as map keys, so parsed and generated
+// moves have to be equal. Position.Annotate returns the flags of a move.
+type Flag uint8
+
+// Possible flags of a move.
+const (
+	Capture Flag = 1 << iota
+	EnPassant
+	DoublePush
+	Castle
+	Promotion
+)
+
+// Has returns whether or not the flag is set.
+func (f Flag) Has(flag Flag) bool {
+	return f&flag == flag
+}
+
+// Parse takes a move in PCN format and return a Move struct. The squares may
+// be separated by a dash or a capture marker (e2-e4, e2xd5), a check or mate
+// marker may follow (e2e4+) and the promotion piece may be written after an
+// equals sign (e7e8=Q). Null is returned if the move is malformed.
 func Parse(algebraic string) Move {
-	if len(algebraic) >= 4 {
+	algebraic = strings.TrimRight(algebraic, "+#")
+	if len(algebraic) >= 5 && (algebraic[2] == '-' || algebraic[2] == 'x') {
+		algebraic = algebraic[:2] + algebraic[3:]
+	}
+	if len(algebraic) == 6 && algebraic[4] == '=' {
+		algebraic = algebraic[:4] + algebraic[5:]
+	}
+	if wellFormed(algebraic) {
 		from := square.Parse(algebraic[0:2])
 		to := square.Parse(algebraic[2:4])
 		promote := piece.None
@@ -44,6 +74,20 @@ func Parse(algebraic string) Move {
 	return Null
 }
 
+// wellFormed returns whether or not the move is two squares followed by an
+// optional promotion piece.
+func wellFormed(algebraic string) bool {
+	if len(algebraic) != 4 && len(algebraic) != 5 {
+		return false
+	}
+	for i := 0; i < 4; i += 2 {
+		if algebraic[i] < 'a' || algebraic[i] > 'h' || algebraic[i+1] < '1' || algebraic[i+1] > '8' {
+			return false
+		}
+	}
+	return len(algebraic) == 4 || strings.ContainsRune("QNBRqnbr", rune(algebraic[4]))
+}
+
 // String will return the move in PCN format.
 func (m Move) String() string {
 	if m.Promote.String() != " " {
@@ -61,3 +105,26 @@ func (m Move) From() square.Square {
 func (m Move) To() square.Square {
 	return m.Destination
 }
+
+// Encode16 packs the move into 16 bits: the source square in bits 0-5, the
+// destination in bits 6-11 and the promotion piece in bits 12-15. Castling is
+// stored as the king's move. Null is encoded as 0 which is never a real move
+// (h1h1). The duration of the move is not kept.
+func (m Move) Encode16() uint16 {
+	if m.Source > square.LastSquare || m.Destination > square.LastSquare {
+		return 0
+	}
+	return uint16(m.Source) | uint16(m.Destination)<<6 | uint16(m.Promote)<<12
+}
+
+// DecodeMove16 unpacks a move that was packed with Encode16.
+func DecodeMove16(m uint16) Move {
+	if m == 0 {
+		return Null
+	}
+	return Move{
+		Source:      square.Square(m & 63),
+		Destination: square.Square((m >> 6) & 63),
+		Promote:     piece.Type(m >> 12),
+	}
+}
@@ -0,0 +1,17 @@
+package move
+
+import (
+	"testing"
+)
+
+func TestEncode16(t *testing.T) {
+	for _, s := range []string{"e2e4", "e7e8q", "a2a1n", "e1g1", "h1a8", "a8h1"} {
+		m := Parse(s)
+		if decoded := DecodeMove16(m.Encode16()); decoded != m {
+			t.Error(s, "decoded as", decoded)
+		}
+	}
+	if Null.Encode16() != 0 || DecodeMove16(0) != Null {
+		t.Error("the null move should be encoded as 0")
+	}
+}
@@ -4,12 +4,21 @@ import (
 	"github.com/reecer/chess/piece"
 	"github.com/reecer/chess/position/move"
 	"github.com/reecer/chess/position/square"
+	"math/rand"
+	"sort"
 )
 
-// LegalMoves returns only the legal moves that can be made.
+// LegalMoves returns only the legal moves that can be made. It is the same as
+// filtering Moves() down to the moves that do not leave the king in check.
+// In a double check only the king can move, so only king moves are generated.
 func (p *Position) LegalMoves() map[move.Move]struct{} {
 	legalMoves := make(map[move.Move]struct{})
-	ml := p.Moves()
+	var ml map[move.Move]struct{}
+	if p.doubleCheck() {
+		ml = p.kingMoves()
+	} else {
+		ml = p.Moves()
+	}
 	for mv := range ml {
 		temp := Copy(p)
 		temp.MakeMove(mv)
@@ -20,9 +29,176 @@ func (p *Position) LegalMoves() map[move.Move]struct{} {
 	return legalMoves
 }
 
+// LegalMovesInto appends the legal moves of the position to buf and returns
+// the extended slice, the same way append does. The result may share buf's
+// backing array, so a search can reuse one buffer at every node instead of
+// allocating a new set of moves each time. The moves are in no particular
+// order.
+func (p *Position) LegalMovesInto(buf []move.Move) []move.Move {
+	start := len(buf)
+	add := func(m move.Move) {
+		buf = append(buf, m)
+	}
+	notToMove := piece.Color((p.ActiveColor + 1) % 2)
+	if p.doubleCheck() {
+		p.genKingMoves(p.ActiveColor, notToMove, p.CastlingRights, add)
+	} else {
+		p.genPawnMoves(p.ActiveColor, notToMove, p.EnPassant, add)
+		p.genKnightMoves(p.ActiveColor, notToMove, add)
+		p.genDiagnalMoves(p.ActiveColor, notToMove, add)
+		p.genStraightMoves(p.ActiveColor, notToMove, add)
+		p.genKingMoves(p.ActiveColor, notToMove, p.CastlingRights, add)
+	}
+	// Filter in place, reusing one scratch position for every move:
+	temp := Copy(p)
+	legal := buf[:start]
+	for _, mv := range buf[start:] {
+		temp.copyFrom(p)
+		temp.MakeMove(mv)
+		if !temp.Check(p.ActiveColor) {
+			legal = append(legal, mv)
+		}
+	}
+	return legal
+}
+
+// copyFrom overwrites the position with src without allocating new
+// bitboards.
+func (p *Position) copyFrom(src *Position) {
+	for c := piece.White; c <= piece.Black; c++ {
+		for pc := piece.Pawn; pc <= piece.King; pc++ {
+			p.bitBoard[c][pc] = src.bitBoard[c][pc]
+		}
+	}
+	p.FiftyMoveCount = src.FiftyMoveCount
+	p.EnPassant = src.EnPassant
+	p.CastlingRights = src.CastlingRights
+	p.ActiveColor = src.ActiveColor
+	p.MoveNumber = src.MoveNumber
+}
+
+// LegalUCIMoves returns the legal moves written in UCI (coordinate) notation.
+// Since LegalMoves has no order, the moves are sorted so the result is the
+// same every time.
+func (p *Position) LegalUCIMoves() []string {
+	var moves []string
+	for m := range p.LegalMoves() {
+		moves = append(moves, m.String())
+	}
+	sort.Strings(moves)
+	return moves
+}
+
+// OnlyMove returns the move to make when there is exactly one legal move.
+func (p *Position) OnlyMove() (move.Move, bool) {
+	legal := p.LegalMoves()
+	if len(legal) != 1 {
+		return move.Null, false
+	}
+	for m := range legal {
+		return m, true
+	}
+	return move.Null, false
+}
+
+// Reachable returns the squares that the piece on the square can legally move
+// to, sorted by square. Promotions to different pieces only count once. If the
+// piece belongs to the side that is not to move, its moves are found as if it
+// were its turn.
+func (p *Position) Reachable(from square.Square) []square.Square {
+	pc := p.OnSquare(from)
+	if pc.Type == piece.None {
+		return nil
+	}
+	seen := make(map[square.Square]bool)
+	var squares []square.Square
+	for m := range p.toMove(pc.Color).LegalMoves() {
+		if m.From() == from && !seen[m.To()] {
+			seen[m.To()] = true
+			squares = append(squares, m.To())
+		}
+	}
+	sort.Slice(squares, func(i, j int) bool { return squares[i] < squares[j] })
+	return squares
+}
+
+// MovesPerPiece returns how many legal moves each of the color's pieces has,
+// keyed by the square it is on. Pieces that can not move, such as pinned or
+// blocked ones, are included with 0. Each promotion counts as its own move.
+// If it is not the color's turn, the moves are found as if it were.
+func (p *Position) MovesPerPiece(c piece.Color) map[square.Square]int {
+	counts := make(map[square.Square]int)
+	pieces := p.occupied(c)
+	for pieces != 0 {
+		sq := bitscan(pieces)
+		counts[square.Square(sq)] = 0
+		pieces ^= (1 << sq)
+	}
+	for m := range p.toMove(c).LegalMoves() {
+		counts[m.From()]++
+	}
+	return counts
+}
+
+// EnPassantCapturers returns the squares of the pawns that can legally capture
+// en passant right now, sorted by square. A pawn that would expose its own king
+// by taking, such as when the king and an enemy rook share the rank of the two
+// pawns, is left out.
+func (p *Position) EnPassantCapturers() []square.Square {
+	if p.EnPassant == square.NoSquare {
+		return nil
+	}
+	var capturers []square.Square
+	for m := range p.LegalMoves() {
+		if m.To() == p.EnPassant && p.OnSquare(m.From()).Type == piece.Pawn {
+			capturers = append(capturers, m.From())
+		}
+	}
+	sort.Slice(capturers, func(i, j int) bool { return capturers[i] < capturers[j] })
+	return capturers
+}
+
+// IsEnPassantAvailable returns whether or not the side to move can legally
+// capture en passant.
+func (p *Position) IsEnPassantAvailable() bool {
+	return len(p.EnPassantCapturers()) > 0
+}
+
+// toMove returns the position with the color to move. If it is not already
+// that color's turn, a copy is made without an en passant square.
+func (p *Position) toMove(c piece.Color) *Position {
+	if p.ActiveColor == c {
+		return p
+	}
+	n := Copy(p)
+	n.SetSideToMove(c)
+	return n
+}
+
+// RandomMove returns one of the legal moves picked at random, or false if
+// there are none. The legal moves are sorted before one is picked so that the
+// same seed always gives the same moves.
+func (p *Position) RandomMove(r *rand.Rand) (move.Move, bool) {
+	moves := p.LegalUCIMoves()
+	if len(moves) == 0 {
+		return move.Null, false
+	}
+	return move.Parse(moves[r.Intn(len(moves))]), true
+}
+
+// PseudoLegalMoves returns the same moves as Moves, including the ones that
+// leave the active color's king in check. Generating them is cheaper than
+// LegalMoves since no move has to be made to see if it leaves the king in
+// check, so an engine can use them and check king safety itself once a move
+// is made.
+func (p *Position) PseudoLegalMoves() map[move.Move]struct{} {
+	return p.Moves()
+}
+
 // Moves returns all moves that a player can make but ignores legality.
 // Moves that put the active color into check are included. Castling moves through
-// an attacked square are not included.
+// an attacked square are not included. These are the pseudo-legal moves, see
+// PseudoLegalMoves.
 func (p *Position) Moves() map[move.Move]struct{} {
 	moves := make(map[move.Move]struct{})
 	add := func(m move.Move) {
@@ -37,6 +213,24 @@ func (p *Position) Moves() map[move.Move]struct{} {
 	return moves
 }
 
+// doubleCheck returns whether or not the side to move is in check by two
+// pieces at once.
+func (p *Position) doubleCheck() bool {
+	notToMove := piece.Color((p.ActiveColor + 1) % 2)
+	king := square.Square(bitscan(p.bitBoard[p.ActiveColor][piece.King]))
+	return popcount(p.attackers(king, notToMove)) >= 2
+}
+
+// kingMoves returns the pseudo-legal moves of the side to move's king.
+func (p *Position) kingMoves() map[move.Move]struct{} {
+	moves := make(map[move.Move]struct{})
+	notToMove := piece.Color((p.ActiveColor + 1) % 2)
+	p.genKingMoves(p.ActiveColor, notToMove, p.CastlingRights, func(m move.Move) {
+		moves[m] = struct{}{}
+	})
+	return moves
+}
+
 func (p *Position) genKnightMoves(toMove, notToMove piece.Color, add func(move.Move)) {
 	//piece.Knights:
 	pieces := p.bitBoard[toMove][piece.Knight]
@@ -107,25 +301,51 @@ func (p *Position) genKingMoves(toMove, notToMove piece.Color, castlingRights [2
 			destinations ^= (1 << to)
 		}
 		// Castles:
-		if castlingRights[toMove][ShortSide] == true {
-			if square.Square(bsr(east[from]&p.occupied(piece.BothColors))) == []square.Square{square.H1, square.H8}[toMove] {
-				if (p.Threatened([]square.Square{square.F1, square.F8}[toMove], notToMove) == false) &&
-					(p.Threatened([]square.Square{square.G1, square.G8}[toMove], notToMove) == false) &&
-					(p.Threatened([]square.Square{square.E1, square.E8}[toMove], notToMove) == false) {
-					add(move.Move{Source: square.Square(from), Destination: []square.Square{square.G1, square.G8}[toMove], Promote: piece.None})
-				}
+		for side := ShortSide; side <= LongSide; side++ {
+			if castlingRights[toMove][side] && p.castlingPathClear(toMove, side) {
+				to := [2][2]square.Square{{square.G1, square.C1}, {square.G8, square.C8}}[toMove][side]
+				add(move.Move{Source: square.Square(from), Destination: to, Promote: piece.None})
 			}
 		}
-		if castlingRights[toMove][LongSide] == true {
-			if square.Square(bsf(west[from]&p.occupied(piece.BothColors))) == []square.Square{square.A1, square.A8}[toMove] {
-				if (p.Threatened([]square.Square{square.D1, square.D8}[toMove], notToMove) == false) &&
-					(p.Threatened([]square.Square{square.C1, square.C8}[toMove], notToMove) == false) &&
-					(p.Threatened([]square.Square{square.E1, square.E8}[toMove], notToMove) == false) {
-					add(move.Move{Source: square.Square(from), Destination: []square.Square{square.C1, square.C8}[toMove], Promote: piece.None})
-				}
-			}
+	}
+}
+
+// CanCastleNow returns whether or not the color can castle to the given side
+// (ShortSide or LongSide) right now. Unlike CastlingRights, which only says
+// whether the king and rook have moved, this also requires that the squares
+// between them are empty and that the king is not in check and does not pass
+// through or land on an attacked square.
+func (p *Position) CanCastleNow(c piece.Color, side uint) bool {
+	return p.CastlingRights[c][side] && p.castlingPathClear(c, side)
+}
+
+// castlingPathClear checks everything needed to castle other than the
+// castling rights.
+func (p *Position) castlingPathClear(c piece.Color, side uint) bool {
+	opponent := []piece.Color{piece.Black, piece.White}[c]
+	king := [2]square.Square{square.E1, square.E8}[c]
+	rook := [2][2]square.Square{{square.H1, square.A1}, {square.H8, square.A8}}[c][side]
+	if p.OnSquare(king) != piece.New(c, piece.King) || p.OnSquare(rook) != piece.New(c, piece.Rook) {
+		return false
+	}
+	// the first piece next to the king has to be the rook:
+	occupied := p.occupied(piece.BothColors)
+	if side == ShortSide && square.Square(bsr(east[king]&occupied)) != rook {
+		return false
+	}
+	if side == LongSide && square.Square(bsf(west[king]&occupied)) != rook {
+		return false
+	}
+	passing := [2][2][2]square.Square{
+		{{square.F1, square.G1}, {square.D1, square.C1}},
+		{{square.F8, square.G8}, {square.D8, square.C8}},
+	}[c][side]
+	for _, sq := range []square.Square{king, passing[0], passing[1]} {
+		if p.Threatened(sq, opponent) {
+			return false
 		}
 	}
+	return true
 }
 
 func (p *Position) genPawnMoves(toMove, notToMove piece.Color, enPassant square.Square, add func(move.Move)) {
@@ -214,3 +434,24 @@ func (p *Position) Threatened(square square.Square, byWho piece.Color) bool {
 	}
 	return false
 }
+
+// attackers returns a bitboard of the specified color's pieces that attack the
+// square.
+func (p *Position) attackers(square square.Square, byWho piece.Color) uint64 {
+	defender := []piece.Color{piece.Black, piece.White}[byWho]
+	attacks := king_moves[square] & p.bitBoard[byWho][piece.King]
+	attacks |= pawn_captures[defender][square] & p.bitBoard[byWho][piece.Pawn]
+	attacks |= knight_moves[square] & p.bitBoard[byWho][piece.Knight]
+	direction := [4][65]uint64{nw, ne, sw, se}
+	scan := [4]func(uint64) uint{bsf, bsf, bsr, bsr}
+	for i := 0; i < 4; i++ {
+		blockerIndex := scan[i](direction[i][square] & p.occupied(piece.BothColors))
+		attacks |= (1 << blockerIndex) & (p.bitBoard[byWho][piece.Bishop] | p.bitBoard[byWho][piece.Queen])
+	}
+	direction = [4][65]uint64{north, west, south, east}
+	for i := 0; i < 4; i++ {
+		blockerIndex := scan[i](direction[i][square] & p.occupied(piece.BothColors))
+		attacks |= (1 << blockerIndex) & (p.bitBoard[byWho][piece.Rook] | p.bitBoard[byWho][piece.Queen])
+	}
+	return attacks
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/reecer/chess/piece"
 	"github.com/reecer/chess/position/move"
 	"github.com/reecer/chess/position/square"
+	"math/rand"
 	"testing"
 )
 
@@ -75,3 +76,277 @@ func TestGenDiagCaps(t *testing.T) {
 func TestEnPassant(t *testing.T) {
 	// TODO
 }
+
+func TestLegalMovesAfterPut(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	if moves := b.LegalMoves(); len(moves) != 3 {
+		t.Error("wanted 3 king moves but got", moves)
+	}
+	// The rook takes b1 and b2 away from the king:
+	b.Put(piece.New(piece.Black, piece.Rook), square.B3)
+	moves := b.LegalMoves()
+	if _, ok := moves[move.Parse("a1a2")]; !ok || len(moves) != 1 {
+		t.Error("wanted only a1a2 but got", moves)
+	}
+	b.ClearSquare(square.B3)
+	if moves := b.LegalMoves(); len(moves) != 3 {
+		t.Error("wanted 3 king moves after clearing the rook but got", moves)
+	}
+}
+
+func TestPseudoLegalMoves(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.E2)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.E8)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.A8)
+	pseudo, legal := b.PseudoLegalMoves(), b.LegalMoves()
+	if len(pseudo) <= len(legal) || len(pseudo) != len(b.Moves()) {
+		t.Error("the pinned knight's moves should only be pseudo-legal", len(pseudo), len(legal))
+	}
+	if _, ok := pseudo[move.Parse("e2c3")]; !ok {
+		t.Error("Nc3 leaves the king in check but is pseudo-legal")
+	}
+	filtered := 0
+	for m := range pseudo {
+		temp := Copy(b)
+		temp.MakeMove(m)
+		if temp.Check(piece.White) {
+			continue
+		}
+		if _, ok := legal[m]; !ok {
+			t.Error(m, "does not leave the king in check but is not legal")
+		}
+		filtered++
+	}
+	if filtered != len(legal) {
+		t.Error("filtering gave", filtered, "moves but there are", len(legal), "legal moves")
+	}
+}
+
+func TestLegalUCIMoves(t *testing.T) {
+	moves := New().LegalUCIMoves()
+	if len(moves) != 20 {
+		t.Error("wanted 20 moves but got", moves)
+	}
+	found := 0
+	for _, m := range moves {
+		if m == "e2e4" || m == "g1f3" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Error("e2e4 and g1f3 should be in", moves)
+	}
+}
+
+func TestOnlyMove(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.B8)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.C2)
+	if m, ok := b.OnlyMove(); !ok || m != move.Parse("a1a2") {
+		t.Error("Ka2 is the only move but got", m, ok)
+	}
+	if _, ok := New().OnlyMove(); ok {
+		t.Error("there are 20 moves in the start position")
+	}
+}
+
+func TestCanCastleNow(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.H1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.A1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.F8)
+	b.CastlingRights = [2][2]bool{{true, true}, {false, false}}
+	if b.CanCastleNow(piece.White, ShortSide) {
+		t.Error("f1 is attacked")
+	}
+	if !b.CanCastleNow(piece.White, LongSide) {
+		t.Error("white should be able to castle long")
+	}
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.B1)
+	if b.CanCastleNow(piece.White, LongSide) {
+		t.Error("b1 is not empty")
+	}
+	if b.CanCastleNow(piece.Black, ShortSide) {
+		t.Error("black has no castling rights")
+	}
+}
+
+func TestRandomMove(t *testing.T) {
+	play := func(seed int64) []move.Move {
+		r := rand.New(rand.NewSource(seed))
+		b := New()
+		var moves []move.Move
+		for i := 0; i < 40; i++ {
+			m, ok := b.RandomMove(r)
+			if !ok {
+				break
+			}
+			if _, legal := b.LegalMoves()[m]; !legal {
+				t.Fatal(m, "is not legal")
+			}
+			b.MakeMove(m)
+			moves = append(moves, m)
+		}
+		return moves
+	}
+	first, second := play(7), play(7)
+	if len(first) != len(second) {
+		t.Fatal("the same seed should play the same game")
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Error("move", i, "differs:", first[i], second[i])
+		}
+	}
+}
+
+func TestDoubleCheckKingMoves(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.B5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.Black, piece.Queen), square.A5)
+	b.QuickPut(piece.New(piece.Black, piece.Knight), square.G6)
+	b.ActiveColor = piece.Black
+	legal := b.LegalMoves()
+	// the queen could take the bishop and the knight could block the rook,
+	// but neither stops both checks:
+	expected := map[move.Move]struct{}{
+		move.Parse("e8d8"): {}, move.Parse("e8f8"): {}, move.Parse("e8f7"): {},
+	}
+	if len(legal) != len(expected) {
+		t.Error("wanted", expected, "but got", legal)
+	}
+	for m := range legal {
+		if _, ok := expected[m]; !ok {
+			t.Error(m, "should not be legal")
+		}
+	}
+}
+
+func TestReachable(t *testing.T) {
+	b := New()
+	squares := b.Reachable(square.G1)
+	if len(squares) != 2 || squares[0] != square.H3 || squares[1] != square.F3 {
+		t.Error("the knight can go to f3 and h3 but got", squares)
+	}
+	if squares := b.Reachable(square.B8); len(squares) != 2 {
+		t.Error("black's knight should also have two squares but got", squares)
+	}
+	if squares := b.Reachable(square.E4); squares != nil {
+		t.Error("there is no piece on e4", squares)
+	}
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.B7)
+	if squares := b.Reachable(square.B7); len(squares) != 1 || squares[0] != square.B8 {
+		t.Error("promotions should be collapsed into b8 but got", squares)
+	}
+}
+
+func TestLegalMovesInto(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	b := New()
+	buf := []move.Move{move.Null}
+	for ply := 0; ply < 200; ply++ {
+		legal := b.LegalMoves()
+		buf = b.LegalMovesInto(buf[:1])
+		if buf[0] != move.Null {
+			t.Fatal("the moves already in the buffer should be kept")
+		}
+		if len(buf)-1 != len(legal) {
+			t.Fatal(b, "wanted", legal, "but got", buf[1:])
+		}
+		for _, m := range buf[1:] {
+			if _, ok := legal[m]; !ok {
+				t.Fatal(b, m, "is not legal")
+			}
+		}
+		m, ok := b.RandomMove(r)
+		if !ok {
+			b = New()
+			continue
+		}
+		b.MakeMove(m)
+	}
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	p := New()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.LegalMoves()
+	}
+}
+
+func BenchmarkLegalMovesInto(b *testing.B) {
+	p := New()
+	buf := make([]move.Move, 0, 256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = p.LegalMovesInto(buf[:0])
+	}
+}
+
+func TestEnPassantCapturers(t *testing.T) {
+	b := New()
+	// 1. e4 a6 2. e5 d5
+	for _, m := range []string{"e2e4", "a7a6", "e4e5", "d7d5"} {
+		b.MakeMove(move.Parse(m))
+	}
+	if capturers := b.EnPassantCapturers(); len(capturers) != 1 || capturers[0] != square.E5 || !b.IsEnPassantAvailable() {
+		t.Error("e5 can take on d6 but got", capturers)
+	}
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A5)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.B5)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.C5)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.H5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	if err := b.SetEnPassant(square.C6, true); err != nil {
+		t.Fatal(err)
+	}
+	if capturers := b.EnPassantCapturers(); capturers != nil || b.IsEnPassantAvailable() {
+		t.Error("bxc6 would leave the king in check from h5 but got", capturers)
+	}
+	if New().IsEnPassantAvailable() {
+		t.Error("there is no en passant square at the start")
+	}
+}
+
+func TestMovesPerPiece(t *testing.T) {
+	counts := New().MovesPerPiece(piece.White)
+	if len(counts) != 16 || counts[square.G1] != 2 || counts[square.E2] != 2 || counts[square.D1] != 0 {
+		t.Error(counts)
+	}
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.E2)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.E8)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.A8)
+	counts = b.MovesPerPiece(piece.White)
+	if n, ok := counts[square.E2]; !ok || n != 0 {
+		t.Error("the pinned knight has no moves but got", n, ok)
+	}
+	if counts[square.E1] != 4 {
+		t.Error("the king has 4 moves but got", counts[square.E1])
+	}
+	if black := b.MovesPerPiece(piece.Black); len(black) != 2 || black[square.A8] != 3 {
+		t.Error(black)
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"github.com/reecer/chess/position/move"
 	"github.com/reecer/chess/position/square"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+// coordinateCapture matches a capture written with both squares: e2xd5
+var coordinateCapture = regexp.MustCompile("^([a-h][1-8])x([a-h][1-8])")
+
 // ParseMove transforms a move written in standard algebraic notation (SAN)
 // to a move written in Pure Coordinate Notation (PCN).
 //
@@ -22,16 +26,18 @@ func (p *Position) ParseMove(san string) (move.Move, error) {
 		return move.Parse(san), nil
 	}
 	color := p.ActiveColor
-	// Check for castling:
-	if san == "O-O" {
+	// Check for castling (which can also give check):
+	castle := strings.Replace(strings.TrimRight(san, "+#"), "0", "O", -1)
+	if castle == "O-O" {
 		return move.Parse([]string{"e1g1", "e8g8"}[color]), nil
 	}
-	if san == "O-O-O" {
+	if castle == "O-O-O" {
 		return move.Parse([]string{"e1c1", "e8c8"}[color]), nil
 	}
 
 	// Strip uneeded characters:
 	san = strings.Replace(san, "-", "", -1)
+	san = coordinateCapture.ReplaceAllString(san, "$1$2")
 
 	// First check to see if it is already in the correct form.
 	PCN := "([a-h][1-8])([a-h][1-8])([QBNRqbnr]?)"
@@ -50,7 +56,11 @@ func (p *Position) ParseMove(san string) (move.Move, error) {
 				}
 			}
 		}
-		return move.Parse(parsed), nil
+		m := move.Parse(parsed)
+		if m == move.Null {
+			return m, errors.New("could not parse '" + san + "'")
+		}
+		return m, nil
 	}
 
 	//	    (piece)    (from)  (from)  (cap) (dest)      (promotion)        (chk  )
@@ -142,3 +152,79 @@ func (p *Position) originOfPiece(pc string, color piece.Color, destination, from
 
 	return "", errors.New("Notation: Can not find source square.")
 }
+
+// ParseLegalMove is like ParseMove but also returns an error if the move is
+// not legal in the position.
+func (p *Position) ParseLegalMove(s string) (move.Move, error) {
+	m, err := p.ParseMove(s)
+	if err != nil {
+		return m, err
+	}
+	if _, legal := p.LegalMoves()[m]; !legal {
+		return m, errors.New("'" + s + "' is illegal")
+	}
+	return m, nil
+}
+
+var uciMove = regexp.MustCompile("^[a-h][1-8][a-h][1-8][qrbn]?$")
+
+// ApplyUCI plays a space separated list of moves in UCI (coordinate) notation,
+// as sent with the UCI command "position startpos moves e2e4 e7e5", and
+// returns the resulting position. The position ApplyUCI is called on is left
+// as it was. If a move is malformed or illegal, the error says which one.
+func (p *Position) ApplyUCI(moves string) (*Position, error) {
+	return p.playUCI(strings.Fields(moves), nil)
+}
+
+// FollowPV plays a principal variation given in UCI notation and returns the
+// final position along with the line written in SAN. The position FollowPV is
+// called on is left as it was. If a move is malformed or illegal, the error
+// says which one.
+func (p *Position) FollowPV(uciMoves []string) (final *Position, san []string, err error) {
+	final, err = p.playUCI(uciMoves, func(m move.Move, before *Position) {
+		san = append(san, before.SAN(m))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return final, san, nil
+}
+
+// playUCI plays the moves on a copy of the position and returns the result.
+// If fn is not nil it is called with each move and the position before it is
+// made.
+func (p *Position) playUCI(uciMoves []string, fn func(m move.Move, before *Position)) (*Position, error) {
+	final := Copy(p)
+	for i, s := range uciMoves {
+		if !uciMove.MatchString(s) {
+			return nil, errors.New("move " + strconv.Itoa(i) + " '" + s + "' is not a UCI move")
+		}
+		m := move.Parse(s)
+		if _, legal := final.LegalMoves()[m]; !legal {
+			return nil, errors.New("move " + strconv.Itoa(i) + " '" + s + "' is illegal")
+		}
+		if fn != nil {
+			fn(m, final)
+		}
+		final.MakeMove(m)
+	}
+	return final, nil
+}
+
+// ConvertMoves plays the moves from the start position and returns each of
+// them written both in SAN and in UCI notation. The moves can be a mix of the
+// two. The start position is left as it was. If a move can not be read or is
+// illegal, the error says which one.
+func ConvertMoves(start *Position, moves []string) (san []string, uci []string, err error) {
+	p := Copy(start)
+	for i, s := range moves {
+		m, err := p.ParseLegalMove(s)
+		if err != nil {
+			return nil, nil, errors.New("move " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		san = append(san, p.SAN(m))
+		uci = append(uci, m.String())
+		p.MakeMove(m)
+	}
+	return san, uci, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/reecer/chess/piece"
 	"github.com/reecer/chess/position/move"
 	"github.com/reecer/chess/position/square"
+	"strings"
 	"testing"
 )
 
@@ -22,3 +23,108 @@ func TestParseNoPromo(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestApplyUCI(t *testing.T) {
+	b := New()
+	p, err := b.ApplyUCI("e2e4 e7e5 g1f3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.OnSquare(square.F3) != piece.New(piece.White, piece.Knight) || p.ActiveColor != piece.Black {
+		t.Error(p)
+	}
+	if b.OnSquare(square.E2) != piece.New(piece.White, piece.Pawn) {
+		t.Error("ApplyUCI should not change the original position")
+	}
+	if _, err := b.ApplyUCI("e2e4 e7e5 e4e5"); err == nil || !strings.Contains(err.Error(), "move 2") {
+		t.Error("e4e5 is illegal and is move 2 but got", err)
+	}
+	if _, err := b.ApplyUCI("e2e4 Nf6"); err == nil {
+		t.Error("Nf6 is not a UCI move")
+	}
+}
+
+func TestFollowPV(t *testing.T) {
+	b := New()
+	final, san, err := b.FollowPV([]string{"f2f3", "e7e5", "g2g4", "d8h4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"f3", "e5", "g4", "Qh4#"}
+	for i := range expected {
+		if i >= len(san) || san[i] != expected[i] {
+			t.Fatal("wanted", expected, "but got", san)
+		}
+	}
+	if len(final.LegalMoves()) != 0 || !final.Check(piece.White) {
+		t.Error("white should be checkmated")
+	}
+	if _, _, err := b.FollowPV([]string{"e2e4", "e2e4"}); err == nil || !strings.Contains(err.Error(), "move 1") {
+		t.Error("the second e2e4 is illegal but got", err)
+	}
+}
+
+func TestParseSeparatedSquares(t *testing.T) {
+	b := New()
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D3)
+	for s, expected := range map[string]string{"e2-e4": "e2e4", "e2xd3": "e2d3"} {
+		if m := move.Parse(s); m != move.Parse(expected) {
+			t.Error("move.Parse", s, "should be", expected, "but got", m)
+		}
+		if m, err := b.ParseMove(s); err != nil || m != move.Parse(expected) {
+			t.Error("ParseMove", s, "should be", expected, "but got", m, err)
+		}
+	}
+	for _, s := range []string{"e2", "e9e4", "e2e4k", "i2i4", "e2--e4"} {
+		if m := move.Parse(s); m != move.Null {
+			t.Error(s, "is malformed but parsed as", m)
+		}
+	}
+}
+
+func TestConvertMoves(t *testing.T) {
+	mixed := []string{"e4", "e7e5", "Nf3", "b8c6", "Bb5", "a7a6", "Bxc6", "d7c6", "O-O"}
+	san, uci, err := ConvertMoves(New(), mixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSAN := []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Bxc6", "dxc6", "O-O"}
+	expectedUCI := []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5c6", "d7c6", "e1g1"}
+	for i := range mixed {
+		if san[i] != expectedSAN[i] || uci[i] != expectedUCI[i] {
+			t.Error("move", i, "wanted", expectedSAN[i], expectedUCI[i], "but got", san[i], uci[i])
+		}
+	}
+	// converting either rendering back gives the same moves:
+	for _, moves := range [][]string{san, uci} {
+		again, _, err := ConvertMoves(New(), moves)
+		if err != nil || strings.Join(again, " ") != strings.Join(san, " ") {
+			t.Error("round trip of", moves, "gave", again, err)
+		}
+	}
+	if _, _, err := ConvertMoves(New(), []string{"e4", "e5", "Ke3"}); err == nil || !strings.Contains(err.Error(), "move 2") {
+		t.Error("Ke3 is illegal but got", err)
+	}
+}
+
+func TestParseMoveSuffixes(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E7)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E2)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.A8)
+	for s, expected := range map[string]string{"e7e8=Q": "e7e8q", "e7e8=n": "e7e8n", "e2e4+": "e2e4", "e7e8q#": "e7e8q", "e7e8=Q+": "e7e8q"} {
+		if m := move.Parse(s); m != move.Parse(expected) {
+			t.Error("move.Parse", s, "should be", expected, "but got", m)
+		}
+		if m, err := b.ParseMove(s); err != nil || m != move.Parse(expected) {
+			t.Error("ParseMove", s, "should be", expected, "but got", m, err)
+		}
+	}
+	for _, s := range []string{"e7e8=", "e7e8=K", "e2e4k"} {
+		if m, err := b.ParseMove(s); err == nil {
+			t.Error(s, "is malformed but parsed as", m)
+		}
+	}
+}
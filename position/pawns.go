@@ -0,0 +1,133 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+)
+
+// PawnPromotionDistance returns how many ranks the pawn on the square is from
+// promoting, or -1 if there is no pawn on the square.
+func (p *Position) PawnPromotionDistance(sq square.Square) int {
+	pc := p.OnSquare(sq)
+	if pc.Type != piece.Pawn {
+		return -1
+	}
+	rank := int(sq)/8 + 1
+	if pc.Color == piece.White {
+		return 8 - rank
+	}
+	return rank - 1
+}
+
+// PromotionRace works out which side queens a pawn first in an endgame with
+// only kings and pawns. A pawn takes part in the race if it is passed, nothing
+// stands in its way and the opponent's king is outside of its square (it can
+// not catch the pawn before it queens). decisive is false if there are other
+// pieces on the board, if neither side has such a pawn or if both sides queen
+// one right after the other, which is too close to call.
+func (p *Position) PromotionRace() (winner piece.Color, decisive bool) {
+	for c := piece.White; c <= piece.Black; c++ {
+		for _, pc := range []piece.Type{piece.Knight, piece.Bishop, piece.Rook, piece.Queen} {
+			if p.bitBoard[c][pc] != 0 {
+				return piece.Neither, false
+			}
+		}
+	}
+	plies := [2]int{-1, -1}
+	for c := piece.White; c <= piece.Black; c++ {
+		moves := p.fastestPromotion(c)
+		if moves == -1 {
+			continue
+		}
+		// the side to move gets there a ply sooner:
+		plies[c] = 2 * moves
+		if c == p.ActiveColor {
+			plies[c]--
+		}
+	}
+	white, black := plies[piece.White], plies[piece.Black]
+	switch {
+	case white == -1 && black == -1:
+		return piece.Neither, false
+	case black == -1, white != -1 && white+1 < black:
+		return piece.White, true
+	case white == -1, black+1 < white:
+		return piece.Black, true
+	}
+	return piece.Neither, false
+}
+
+// fastestPromotion returns the fewest moves that one of the color's pawns
+// needs to queen without being stopped, or -1 if none of them can.
+func (p *Position) fastestPromotion(c piece.Color) int {
+	opponent := []piece.Color{piece.Black, piece.White}[c]
+	promotion := []int{7, 0}[c]
+	best := -1
+	pawns := p.bitBoard[c][piece.Pawn]
+	for pawns != 0 {
+		sq := bitscan(pawns)
+		pawns ^= (1 << sq)
+		if !p.unobstructed(c, sq) {
+			continue
+		}
+		rank, file := int(sq)/8, int(sq)%8
+		moves := abs(promotion - rank)
+		if rank == []int{1, 6}[c] {
+			// the first move can be a double push:
+			moves--
+		}
+		if king := p.bitBoard[opponent][piece.King]; king != 0 {
+			// the rule of the square:
+			reach := squareDistance(bitscan(king), uint(promotion*8+file))
+			if opponent == p.ActiveColor {
+				reach--
+			}
+			if reach <= moves {
+				continue
+			}
+		}
+		if best == -1 || moves < best {
+			best = moves
+		}
+	}
+	return best
+}
+
+// unobstructed returns whether or not the color's pawn on the square is passed
+// and has nothing in front of it.
+func (p *Position) unobstructed(c piece.Color, sq uint) bool {
+	opponent := []piece.Color{piece.Black, piece.White}[c]
+	all := p.occupied(piece.BothColors)
+	enemyPawns := p.bitBoard[opponent][piece.Pawn]
+	forward := []int{1, -1}[c]
+	rank, file := int(sq)/8, int(sq)%8
+	for r := rank + forward; r >= 0 && r <= 7; r += forward {
+		if all&(1<<uint(r*8+file)) != 0 {
+			return false
+		}
+		for f := file - 1; f <= file+1; f++ {
+			if f >= 0 && f <= 7 && enemyPawns&(1<<uint(r*8+f)) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// squareDistance returns how many king moves it takes to go between the
+// squares.
+func squareDistance(a, b uint) int {
+	ranks := abs(int(a/8) - int(b/8))
+	files := abs(int(a%8) - int(b%8))
+	if ranks > files {
+		return ranks
+	}
+	return files
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
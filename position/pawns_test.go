@@ -0,0 +1,64 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestPawnPromotionDistance(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.A7)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.A2)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E2)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.B1)
+	expected := map[square.Square]int{square.A7: 1, square.A2: 1, square.E2: 6, square.B1: -1, square.H5: -1}
+	for sq, distance := range expected {
+		if got := b.PawnPromotionDistance(sq); got != distance {
+			t.Error(sq, "wanted", distance, "but got", got)
+		}
+	}
+}
+
+func TestPromotionRace(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.H1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.A5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	if winner, decisive := b.PromotionRace(); winner != piece.White || !decisive {
+		t.Error("the king on h8 is outside of the square but got", winner, decisive)
+	}
+	b.ClearSquare(square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.D5)
+	if _, decisive := b.PromotionRace(); decisive {
+		t.Error("the king on d5 catches the pawn")
+	}
+	b.SetSideToMove(piece.Black)
+	b.ClearSquare(square.D5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E5)
+	if _, decisive := b.PromotionRace(); decisive {
+		t.Error("with black to move the king on e5 catches the pawn")
+	}
+}
+
+func TestPromotionRaceBothSides(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.A6)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.H4)
+	if winner, decisive := b.PromotionRace(); winner != piece.White || !decisive {
+		t.Error("white queens two moves sooner but got", winner, decisive)
+	}
+	b.SetSideToMove(piece.Black)
+	if _, decisive := b.PromotionRace(); decisive {
+		t.Error("both sides queen one after the other")
+	}
+	b.QuickPut(piece.New(piece.Black, piece.Knight), square.B8)
+	if _, decisive := b.PromotionRace(); decisive {
+		t.Error("only pure pawn endgames are decided")
+	}
+}
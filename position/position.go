@@ -67,13 +67,7 @@ func newBitboards() map[piece.Color]map[piece.Type]uint64 {
 // New returns a game board in the opening position. If you want
 // a blank board, use Clear().
 func New() *Position {
-	p := &Position{
-		bitBoard:       newBitboards(),
-		CastlingRights: [2][2]bool{{true, true}, {true, true}},
-		EnPassant:      square.NoSquare,
-		MoveNumber:     1,
-		ActiveColor:    piece.White,
-	}
+	p := &Position{bitBoard: newBitboards()}
 	p.Reset()
 	return p
 }
@@ -82,6 +76,7 @@ func New() *Position {
 func Copy(p *Position) *Position {
 	n := &Position{
 		bitBoard:       newBitboards(),
+		FiftyMoveCount: p.FiftyMoveCount,
 		EnPassant:      p.EnPassant,
 		CastlingRights: p.CastlingRights,
 		ActiveColor:    p.ActiveColor,
@@ -141,10 +136,23 @@ func (p *Position) Clear() {
 	p.bitBoard = newBitboards()
 }
 
-// Reset puts the pieces in the new game position.
+// Reset puts the pieces in the new game position. White is to move, both
+// sides can castle, there is no en passant square and the move counters start
+// over. The position's bitboards are reused.
 func (p *Position) Reset() {
+	if p.bitBoard == nil {
+		p.bitBoard = newBitboards()
+	}
+	p.CastlingRights = [2][2]bool{{true, true}, {true, true}}
+	p.EnPassant = square.NoSquare
+	p.ActiveColor = piece.White
+	p.MoveNumber = 1
+	p.FiftyMoveCount = 0
 	// puts the pieces in their starting/newgame positions
 	for color := piece.Color(0); color < 2; color = color + 1 {
+		for t := range p.bitBoard[color] {
+			p.bitBoard[color][t] = 0
+		}
 		//Pawns first:
 		p.bitBoard[color][piece.Pawn] = 255 << (8 + (color * 8 * 5))
 		//Then the rest of the pieces:
@@ -168,6 +176,25 @@ func (p *Position) OnSquare(s square.Square) piece.Piece {
 	return piece.New(piece.Neither, piece.None)
 }
 
+// PlacedPiece is a piece along with the square that it is on.
+type PlacedPiece struct {
+	Piece  piece.Piece
+	Square square.Square
+}
+
+// PieceList returns every piece on the board with its square, ordered by
+// square (h1 first, a8 last). It is meant for drawing the board.
+func (p *Position) PieceList() []PlacedPiece {
+	var pieces []PlacedPiece
+	all := p.occupied(piece.BothColors)
+	for sq := square.Square(0); sq <= square.LastSquare; sq++ {
+		if all&(1<<sq) != 0 {
+			pieces = append(pieces, PlacedPiece{p.OnSquare(sq), sq})
+		}
+	}
+	return pieces
+}
+
 // Occupied returns a bitBoard with all of the specified colors pieces.
 func (p *Position) occupied(c piece.Color) uint64 {
 	var mask uint64
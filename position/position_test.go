@@ -208,3 +208,46 @@ func TestBitBoardPrint(t *testing.T) {
 	}
 
 }
+
+func TestReset(t *testing.T) {
+	b := New()
+	for _, m := range []string{"e2e4", "d7d5", "e4d5", "g8f6", "e1e2"} {
+		b.MakeMove(move.Parse(m))
+	}
+	white := b.bitBoard[piece.White]
+	b.Reset()
+	fresh := New()
+	if b.ActiveColor != fresh.ActiveColor || b.CastlingRights != fresh.CastlingRights || b.EnPassant != fresh.EnPassant ||
+		b.MoveNumber != fresh.MoveNumber || b.FiftyMoveCount != fresh.FiftyMoveCount {
+		t.Error("state was not reset:", b.ActiveColor, b.CastlingRights, b.EnPassant, b.MoveNumber, b.FiftyMoveCount)
+	}
+	for c := piece.White; c <= piece.Black; c++ {
+		for pc := piece.Pawn; pc <= piece.King; pc++ {
+			if b.bitBoard[c][pc] != fresh.bitBoard[c][pc] {
+				t.Error(piece.New(c, pc), "is not in its starting position")
+			}
+		}
+	}
+	white[piece.Rook] = 0
+	if b.bitBoard[piece.White][piece.Rook] != 0 {
+		t.Error("Reset should reuse the bitboards")
+	}
+}
+
+func TestPieceList(t *testing.T) {
+	pieces := New().PieceList()
+	if len(pieces) != 32 {
+		t.Fatal("wanted 32 pieces but got", len(pieces))
+	}
+	if pieces[0] != (PlacedPiece{piece.New(piece.White, piece.Rook), square.H1}) {
+		t.Error("h1 should come first but got", pieces[0])
+	}
+	if last := pieces[31]; last != (PlacedPiece{piece.New(piece.Black, piece.Rook), square.A8}) {
+		t.Error("a8 should come last but got", last)
+	}
+	for i := 1; i < len(pieces); i++ {
+		if pieces[i-1].Square >= pieces[i].Square {
+			t.Error("pieces are not ordered by square:", pieces[i-1], pieces[i])
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"strings"
+)
+
+// SAN returns the move written in standard algebraic notation (SAN). The move
+// is assumed to be legal in the position. ex: e4, Nbd7, exd6, e8=Q, O-O, Qh5#
+func (p *Position) SAN(m move.Move) string {
+	from, to := m.From(), m.To()
+	moving := p.OnSquare(from)
+	flags := p.Annotate(m)
+	var san string
+	switch {
+	case flags.Has(move.Castle) && to < from:
+		san = "O-O"
+	case flags.Has(move.Castle):
+		san = "O-O-O"
+	case moving.Type == piece.Pawn:
+		if flags.Has(move.Capture) {
+			san = from.String()[:1] + "x"
+		}
+		san += to.String()
+		if m.Promote != piece.None {
+			san += "=" + strings.ToUpper(m.Promote.String())
+		}
+	default:
+		san = strings.ToUpper(moving.Type.String()) + p.disambiguate(m, moving)
+		if flags.Has(move.Capture) {
+			san += "x"
+		}
+		san += to.String()
+	}
+	after := Copy(p)
+	after.MakeMove(m)
+	if after.Check(after.ActiveColor) {
+		if len(after.LegalMoves()) == 0 {
+			return san + "#"
+		}
+		return san + "+"
+	}
+	return san
+}
+
+// nagSymbols are the move suffixes of the move assessment NAGs.
+var nagSymbols = map[int]string{1: "!", 2: "?", 3: "!!", 4: "??", 5: "!?", 6: "?!"}
+
+// SANWithNAG returns the move in SAN followed by the symbol of the first of
+// the Numeric Annotation Glyphs that assesses the move, ex: Nf3! or Qh7#!!
+// NAGs without a symbol, such as the ones about the position, are left out.
+func (p *Position) SANWithNAG(m move.Move, nags []int) string {
+	san := p.SAN(m)
+	for _, nag := range nags {
+		if symbol, ok := nagSymbols[nag]; ok {
+			return san + symbol
+		}
+	}
+	return san
+}
+
+// Annotate returns the flags that describe what the move does in the position:
+// whether it is a capture (and en passant), a double pawn push, castling or a
+// promotion. The move does not have to be legal.
+func (p *Position) Annotate(m move.Move) move.Flag {
+	from, to := m.From(), m.To()
+	moving := p.OnSquare(from)
+	var flags move.Flag
+	if p.isCapture(m) {
+		flags |= move.Capture
+	}
+	switch moving.Type {
+	case piece.Pawn:
+		if to == p.EnPassant {
+			flags |= move.EnPassant
+		}
+		if to == from+16 || to == from-16 {
+			flags |= move.DoublePush
+		}
+		if m.Promote != piece.None {
+			flags |= move.Promotion
+		}
+	case piece.King:
+		if from%8 == 3 && (to == from-2 || to == from+2) {
+			flags |= move.Castle
+		}
+	}
+	return flags
+}
+
+// disambiguate returns the file, rank or square of the move's source that is
+// needed to tell it apart from the moves of other pieces of the same type to
+// the same square.
+func (p *Position) disambiguate(m move.Move, moving piece.Piece) string {
+	var sameFile, sameRank, ambiguous bool
+	for other := range p.LegalMoves() {
+		if other.To() != m.To() || other.From() == m.From() || p.OnSquare(other.From()) != moving {
+			continue
+		}
+		ambiguous = true
+		if other.From()%8 == m.From()%8 {
+			sameFile = true
+		}
+		if other.From()/8 == m.From()/8 {
+			sameRank = true
+		}
+	}
+	from := m.From().String()
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
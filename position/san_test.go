@@ -0,0 +1,102 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestSANDisambiguation(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.H1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.B1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.F1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.A5)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.A3)
+	expected := map[string]string{
+		"b1d2": "Nbd2",
+		"f1d2": "Nfd2",
+		"b1c3": "Nc3",
+		"a1a3": "R1xa3",
+		"a5a3": "R5xa3",
+	}
+	for m, san := range expected {
+		if got := b.SAN(move.Parse(m)); got != san {
+			t.Error(m, "wanted", san, "but got", got)
+		}
+	}
+}
+
+func TestSANPawnsAndCastles(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.H1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.B7)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E4)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.F8)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.A8)
+	expected := map[string]string{
+		"e1g1":  "O-O+",
+		"e4d5":  "exd5",
+		"e4e5":  "e5",
+		"b7a8q": "bxa8=Q+",
+		"b7b8n": "b8=N",
+	}
+	for m, san := range expected {
+		if got := b.SAN(move.Parse(m)); got != san {
+			t.Error(m, "wanted", san, "but got", got)
+		}
+	}
+	if m, err := b.ParseMove("O-O+"); err != nil || m != move.Parse("e1g1") {
+		t.Error("O-O+ should parse as e1g1 but got", m, err)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	b := New()
+	if f := b.Annotate(move.Parse("e2e4")); !f.Has(move.DoublePush) || f.Has(move.Capture) {
+		t.Error("e2e4 is a double push but got", f)
+	}
+	b, _ = b.ApplyUCI("e2e4 d7d5")
+	if f := b.Annotate(move.Parse("e4d5")); f != move.Capture {
+		t.Error("e4d5 is a capture but got", f)
+	}
+	b, _ = b.ApplyUCI("e4e5 f7f5")
+	if f := b.Annotate(move.Parse("e5f6")); !f.Has(move.Capture | move.EnPassant) {
+		t.Error("e5f6 is an en passant capture but got", f)
+	}
+	b, _ = b.ApplyUCI("g1f3 b8c6 f1e2 c6d4")
+	if f := b.Annotate(move.Parse("e1g1")); f != move.Castle {
+		t.Error("e1g1 is castling but got", f)
+	}
+}
+
+func TestSANWithNAG(t *testing.T) {
+	if san := New().SANWithNAG(move.Parse("g1f3"), []int{1}); san != "Nf3!" {
+		t.Error("wanted Nf3! but got", san)
+	}
+	if san := New().SANWithNAG(move.Parse("g1f3"), []int{10}); san != "Nf3" {
+		t.Error("$10 has no move symbol but got", san)
+	}
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Queen), square.H5)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.D3)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.G8)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.F8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.F7)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.G7)
+	if san := b.SANWithNAG(move.Parse("h5h7"), nil); san != "Qh7#" {
+		t.Error("wanted Qh7# but got", san)
+	}
+	if san := b.SANWithNAG(move.Parse("h5h7"), []int{18, 3}); san != "Qh7#!!" {
+		t.Error("wanted Qh7#!! but got", san)
+	}
+}
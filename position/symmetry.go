@@ -0,0 +1,72 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+)
+
+// Congruent returns which transform of the board maps a onto b:
+//
+//	identity         nothing changes
+//	mirror           the a and h files swap
+//	flip             the first and eighth ranks swap
+//	rotate180        both of the above
+//	color-swap-flip  the ranks swap along with the colors of the pieces and
+//	                 the side to move, which is the same position seen from
+//	                 the other player's side
+//
+// Only the pieces and the side to move are compared. ok is false if none of
+// the transforms match.
+func Congruent(a, b *Position) (transform string, ok bool) {
+	transforms := []struct {
+		name   string
+		square func(square.Square) square.Square
+		swap   bool
+	}{
+		{"identity", func(sq square.Square) square.Square { return sq }, false},
+		{"mirror", mirrorSquare, false},
+		{"flip", flipSquare, false},
+		{"rotate180", func(sq square.Square) square.Square { return mirrorSquare(flipSquare(sq)) }, false},
+		{"color-swap-flip", flipSquare, true},
+	}
+	for _, t := range transforms {
+		if a.mapsOnto(b, t.square, t.swap) {
+			return t.name, true
+		}
+	}
+	return "", false
+}
+
+// mapsOnto returns whether or not moving each of p's pieces to the square
+// given by transform, and swapping colors if swap is set, gives other.
+func (p *Position) mapsOnto(other *Position, transform func(square.Square) square.Square, swap bool) bool {
+	swapColor := func(c piece.Color) piece.Color {
+		if swap && c != piece.Neither {
+			return []piece.Color{piece.Black, piece.White}[c]
+		}
+		return c
+	}
+	if swapColor(p.ActiveColor) != other.ActiveColor {
+		return false
+	}
+	for sq := square.Square(0); sq <= square.LastSquare; sq++ {
+		pc := p.OnSquare(sq)
+		pc.Color = swapColor(pc.Color)
+		if other.OnSquare(transform(sq)) != pc {
+			return false
+		}
+	}
+	return true
+}
+
+// mirrorSquare returns the square on the other side of the board from left to
+// right: a1 becomes h1.
+func mirrorSquare(sq square.Square) square.Square {
+	return sq/8*8 + (7 - sq%8)
+}
+
+// flipSquare returns the square on the other side of the board from top to
+// bottom: a1 becomes a8.
+func flipSquare(sq square.Square) square.Square {
+	return (7-sq/8)*8 + sq%8
+}
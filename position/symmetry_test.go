@@ -0,0 +1,42 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestCongruent(t *testing.T) {
+	a := New()
+	a.Clear()
+	a.QuickPut(piece.New(piece.White, piece.King), square.B1)
+	a.QuickPut(piece.New(piece.White, piece.Pawn), square.C2)
+	a.QuickPut(piece.New(piece.Black, piece.King), square.A8)
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.G1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.F2)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	if transform, ok := Congruent(a, b); !ok || transform != "mirror" {
+		t.Error("wanted mirror but got", transform, ok)
+	}
+	if transform, ok := Congruent(a, a); !ok || transform != "identity" {
+		t.Error("wanted identity but got", transform, ok)
+	}
+	c := New()
+	c.Clear()
+	c.QuickPut(piece.New(piece.Black, piece.King), square.B8)
+	c.QuickPut(piece.New(piece.Black, piece.Pawn), square.C7)
+	c.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	c.SetSideToMove(piece.Black)
+	if transform, ok := Congruent(a, c); !ok || transform != "color-swap-flip" {
+		t.Error("wanted color-swap-flip but got", transform, ok)
+	}
+	c.SetSideToMove(piece.White)
+	if transform, ok := Congruent(a, c); ok {
+		t.Error("the side to move should also swap but got", transform)
+	}
+	if transform, ok := Congruent(New(), b); ok {
+		t.Error("the start position is not congruent to b but got", transform)
+	}
+}
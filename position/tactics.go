@@ -0,0 +1,334 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"github.com/reecer/chess/position/square"
+	"sort"
+)
+
+// Pin is a line of three pieces: the Pinner (a bishop, rook or queen) attacks
+// the Pinned piece which can't move off of the line without exposing the
+// piece Behind it. When returned as a skewer, Pinned is the more valuable
+// piece in front that is forced to move out of the way.
+type Pin struct {
+	Pinned square.Square
+	Pinner square.Square
+	Behind square.Square
+}
+
+// Pins returns the pins against the specified color's pieces. A pin is
+// absolute when the piece behind is the king, otherwise it is relative and the
+// piece behind has to be worth more than the pinned piece.
+func (p *Position) Pins(c piece.Color) []Pin {
+	return p.lines(c, func(front, behind piece.Piece) bool {
+		return behind.Type == piece.King || pieceValues[behind.Type] > pieceValues[front.Type]
+	})
+}
+
+// Skewers returns the skewers against the specified color's pieces, which is
+// when the piece in front is worth more than the piece behind it.
+func (p *Position) Skewers(c piece.Color) []Pin {
+	return p.lines(c, func(front, behind piece.Piece) bool {
+		return pieceValues[front.Type] > pieceValues[behind.Type]
+	})
+}
+
+// lines finds every enemy slider that has two of c's pieces lined up on one of
+// its rays and keeps the ones where include is true.
+func (p *Position) lines(c piece.Color, include func(front, behind piece.Piece) bool) []Pin {
+	var found []Pin
+	opponent := []piece.Color{piece.Black, piece.White}[c]
+	occupied := p.occupied(piece.BothColors)
+	sliders := []struct {
+		direction [4][65]uint64
+		pieces    uint64
+	}{
+		{[4][65]uint64{nw, ne, sw, se}, p.bitBoard[opponent][piece.Bishop] | p.bitBoard[opponent][piece.Queen]},
+		{[4][65]uint64{north, west, south, east}, p.bitBoard[opponent][piece.Rook] | p.bitBoard[opponent][piece.Queen]},
+	}
+	scan := [4]func(uint64) uint{bsf, bsf, bsr, bsr}
+	for _, slider := range sliders {
+		pieces := slider.pieces
+		for pieces != 0 {
+			from := bitscan(pieces)
+			for i := 0; i < 4; i++ {
+				first := scan[i](slider.direction[i][from] & occupied)
+				second := scan[i](slider.direction[i][first] & occupied)
+				if first == 64 || second == 64 {
+					continue
+				}
+				front, behind := p.OnSquare(square.Square(first)), p.OnSquare(square.Square(second))
+				if front.Color == c && behind.Color == c && include(front, behind) {
+					found = append(found, Pin{
+						Pinned: square.Square(first),
+						Pinner: square.Square(from),
+						Behind: square.Square(second),
+					})
+				}
+			}
+			pieces ^= (1 << from)
+		}
+	}
+	return found
+}
+
+// IsQuiet returns whether or not the position is quiet: the side to move is
+// not in check and has no legal captures (en passant included). Checks that
+// the side to move could give and promotions without a capture do not count.
+func (p *Position) IsQuiet() bool {
+	if p.Check(p.ActiveColor) {
+		return false
+	}
+	for m := range p.LegalMoves() {
+		if p.isCapture(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCapture returns whether or not the move captures a piece.
+func (p *Position) isCapture(m move.Move) bool {
+	if p.OnSquare(m.To()).Type != piece.None {
+		return true
+	}
+	return m.To() == p.EnPassant && p.OnSquare(m.From()).Type == piece.Pawn
+}
+
+// Captures returns the legal moves that capture a piece, en passant included,
+// sorted by their UCI notation.
+func (p *Position) Captures() []move.Move {
+	return p.legalMovesWhere(p.isCapture)
+}
+
+// CaptureTargets returns the enemy pieces that the color can legally capture,
+// keyed by the square they are on. A pawn that can be taken en passant is
+// listed on the square it is on rather than the en passant square. It can be
+// used to order captures by the value of the victim.
+func (p *Position) CaptureTargets(c piece.Color) map[square.Square]piece.Piece {
+	pos := p.toMove(c)
+	targets := make(map[square.Square]piece.Piece)
+	for _, m := range pos.Captures() {
+		to := m.To()
+		if pos.OnSquare(to).Type == piece.None {
+			// en passant, the pawn is behind the square:
+			to = square.Square(int(to) + []int{-8, 8}[c])
+		}
+		targets[to] = pos.OnSquare(to)
+	}
+	return targets
+}
+
+// CheckingMoves returns the legal moves that give check, sorted by their UCI
+// notation.
+func (p *Position) CheckingMoves() []move.Move {
+	return p.legalMovesWhere(p.givesCheck)
+}
+
+// PromotionMoves returns the legal moves that promote a pawn, one for each
+// piece it can promote to, sorted by their UCI notation.
+func (p *Position) PromotionMoves() []move.Move {
+	return p.legalMovesWhere(func(m move.Move) bool {
+		return m.Promote != piece.None
+	})
+}
+
+// ForcingMoves returns the legal moves that a tactics trainer or a quiescence
+// search would look at: every check, every capture and every move that
+// threatens mate. A move threatens mate if, were the opponent to pass, the
+// side that made it would have a move that checkmates. The moves are sorted
+// by their UCI notation.
+func (p *Position) ForcingMoves() []move.Move {
+	return p.legalMovesWhere(func(m move.Move) bool {
+		return p.givesCheck(m) || p.isCapture(m) || p.threatensMate(m)
+	})
+}
+
+// DefensiveMoves returns the color's legal moves after which one more of its
+// pieces attacks the square, sorted by their UCI notation. That includes
+// moving a piece so that it defends the square and moving a piece out of the
+// way of one behind it. Moves of the piece on the square itself are left out.
+// If it is not the color's turn, the moves are found as if it were.
+func (p *Position) DefensiveMoves(sq square.Square, c piece.Color) []move.Move {
+	pos := p.toMove(c)
+	defenders := popcount(pos.attackers(sq, c))
+	return pos.legalMovesWhere(func(m move.Move) bool {
+		if m.From() == sq {
+			return false
+		}
+		after := Copy(pos)
+		after.MakeMove(m)
+		return popcount(after.attackers(sq, c)) > defenders
+	})
+}
+
+// StalemateTraps returns the squares that the color's pieces can move to
+// where the move would stalemate the opponent, sorted by square. In an
+// endgame like a queen against a lone king, these are the moves the winning
+// side has to avoid.
+func (p *Position) StalemateTraps(c piece.Color) []square.Square {
+	pos := p.toMove(c)
+	seen := make(map[square.Square]bool)
+	var traps []square.Square
+	for _, m := range pos.LegalMovesInto(nil) {
+		if seen[m.To()] {
+			continue
+		}
+		after := Copy(pos)
+		after.MakeMove(m)
+		if !after.Check(after.ActiveColor) && len(after.LegalMovesInto(nil)) == 0 {
+			seen[m.To()] = true
+			traps = append(traps, m.To())
+		}
+	}
+	sort.Slice(traps, func(i, j int) bool {
+		return traps[i] < traps[j]
+	})
+	return traps
+}
+
+// legalMovesWhere returns the legal moves that keep returns true for, sorted
+// by their UCI notation.
+func (p *Position) legalMovesWhere(keep func(move.Move) bool) []move.Move {
+	var moves []move.Move
+	for _, m := range p.LegalMovesInto(nil) {
+		if keep(m) {
+			moves = append(moves, m)
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].String() < moves[j].String()
+	})
+	return moves
+}
+
+// givesCheck returns whether or not the move puts the opponent in check.
+func (p *Position) givesCheck(m move.Move) bool {
+	temp := Copy(p)
+	temp.MakeMove(m)
+	return temp.Check(temp.ActiveColor)
+}
+
+// threatensMate returns whether or not the side to move could checkmate on
+// its next move if the opponent passed after the move was made.
+func (p *Position) threatensMate(m move.Move) bool {
+	temp := Copy(p)
+	temp.MakeMove(m)
+	temp.SetSideToMove(p.ActiveColor)
+	for _, next := range temp.LegalMovesInto(nil) {
+		if !temp.givesCheck(next) {
+			continue
+		}
+		after := Copy(temp)
+		after.MakeMove(next)
+		if len(after.LegalMovesInto(nil)) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ControlledSquares returns how many of the color's pieces attack each square.
+// Squares that are not attacked are left out. A piece defending one of its own
+// pieces still counts as controlling that square.
+func (p *Position) ControlledSquares(c piece.Color) map[square.Square]int {
+	control := make(map[square.Square]int)
+	for sq := square.Square(0); sq <= square.LastSquare; sq++ {
+		if n := popcount(p.attackers(sq, c)); n > 0 {
+			control[sq] = int(n)
+		}
+	}
+	return control
+}
+
+// BestCaptureSEE returns the static exchange evaluation (SEE) of capturing the
+// piece on the square with the color's least valuable attacker. It is the
+// material in centipawns that the color comes out ahead (or behind, if
+// negative) after both sides have made the recaptures on the square that pay
+// off for them. Pins and checks are not taken into account. 0 is returned if
+// the color can not capture on the square.
+func (p *Position) BestCaptureSEE(sq square.Square, by piece.Color) int {
+	captured := p.exchange(sq, by)
+	if len(captured) == 0 {
+		return 0
+	}
+	// Going backwards, each side only recaptures if it comes out ahead:
+	score := 0
+	for i := len(captured) - 1; i > 0; i-- {
+		if score = pieceValues[captured[i].Type] - score; score < 0 {
+			score = 0
+		}
+	}
+	return pieceValues[captured[0].Type] - score
+}
+
+// ExchangeSequence returns the pieces that are captured on the square, in
+// order, when the side to move starts an exchange there and both sides keep
+// capturing with their least valuable attacker for as long as it does not
+// lose them material. It is the trace behind BestCaptureSEE. The sequence is
+// empty if the side to move can not capture on the square without losing
+// material.
+func (p *Position) ExchangeSequence(sq square.Square) []piece.Piece {
+	captured := p.exchange(sq, p.ActiveColor)
+	// Going backwards, work out which captures are worth making:
+	take := make([]bool, len(captured))
+	score := 0
+	for i := len(captured) - 1; i >= 0; i-- {
+		gain := pieceValues[captured[i].Type] - score
+		take[i] = gain >= 0
+		if take[i] {
+			score = gain
+		} else {
+			score = 0
+		}
+	}
+	for i := range captured {
+		if !take[i] {
+			return captured[:i]
+		}
+	}
+	return captured
+}
+
+// exchange returns the pieces captured on the square, in order, when the two
+// sides take turns capturing on it with their least valuable attacker,
+// starting with the given color. Pieces that are uncovered behind an
+// attacker (x-rays) join in. A king only captures if the square is no longer
+// defended.
+func (p *Position) exchange(sq square.Square, by piece.Color) []piece.Piece {
+	target := p.OnSquare(sq)
+	if target.Type == piece.None || target.Color == by {
+		return nil
+	}
+	var captured []piece.Piece
+	temp := Copy(p)
+	side := by
+	for {
+		opponent := []piece.Color{piece.Black, piece.White}[side]
+		from, attacker, ok := temp.leastValuableAttacker(sq, side)
+		if !ok {
+			break
+		}
+		temp.ClearSquare(from)
+		if attacker.Type == piece.King && temp.attackers(sq, opponent) != 0 {
+			break
+		}
+		captured = append(captured, target)
+		target = attacker
+		side = opponent
+	}
+	return captured
+}
+
+// leastValuableAttacker returns the square and the piece of the color's
+// cheapest piece that attacks the square.
+func (p *Position) leastValuableAttacker(sq square.Square, c piece.Color) (square.Square, piece.Piece, bool) {
+	attackers := p.attackers(sq, c)
+	for pc := piece.Pawn; pc <= piece.King; pc++ {
+		if pieces := attackers & p.bitBoard[c][pc]; pieces != 0 {
+			return square.Square(bitscan(pieces)), piece.New(c, pc), true
+		}
+	}
+	return square.NoSquare, piece.New(piece.Neither, piece.None), false
+}
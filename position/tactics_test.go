@@ -0,0 +1,320 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/move"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestAbsolutePin(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.D2)
+	b.QuickPut(piece.New(piece.Black, piece.Bishop), square.B4)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	pins := b.Pins(piece.White)
+	expected := Pin{Pinned: square.D2, Pinner: square.B4, Behind: square.E1}
+	if len(pins) != 1 || pins[0] != expected {
+		t.Error("wanted", expected, "but got", pins)
+	}
+	if len(b.Pins(piece.Black)) != 0 || len(b.Skewers(piece.White)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestRelativePin(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.G1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.G8)
+	b.QuickPut(piece.New(piece.Black, piece.Knight), square.E5)
+	b.QuickPut(piece.New(piece.Black, piece.Queen), square.E8)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.E1)
+	pins := b.Pins(piece.Black)
+	expected := Pin{Pinned: square.E5, Pinner: square.E1, Behind: square.E8}
+	if len(pins) != 1 || pins[0] != expected {
+		t.Error("wanted", expected, "but got", pins)
+	}
+}
+
+func TestSkewer(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.G1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.D5)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.G8)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.A2)
+	skewers := b.Skewers(piece.Black)
+	expected := Pin{Pinned: square.D5, Pinner: square.A2, Behind: square.G8}
+	if len(skewers) != 1 || skewers[0] != expected {
+		t.Error("wanted", expected, "but got", skewers)
+	}
+	if len(b.Pins(piece.Black)) != 0 {
+		t.Error("a king in front is not pinned")
+	}
+}
+
+func TestIsQuiet(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E4)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.E5)
+	if !b.IsQuiet() {
+		t.Error("locked pawns with nothing to capture should be quiet")
+	}
+	b.QuickPut(piece.New(piece.Black, piece.Knight), square.D5)
+	if b.IsQuiet() {
+		t.Error("the knight on d5 is hanging")
+	}
+	b.ClearSquare(square.D5)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.A1)
+	if b.IsQuiet() {
+		t.Error("white is in check")
+	}
+}
+
+func TestControlledSquares(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.F3)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.D4)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.E1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	control := b.ControlledSquares(piece.White)
+	if control[square.E5] != 3 {
+		t.Error("e5 is attacked by the knight, pawn and rook but got", control[square.E5])
+	}
+	if control[square.C5] != 1 || control[square.B2] != 1 {
+		t.Error("c5 and b2 are attacked once", control[square.C5], control[square.B2])
+	}
+	if _, ok := control[square.H8]; ok {
+		t.Error("white does not attack h8")
+	}
+	if black := b.ControlledSquares(piece.Black); len(black) != 3 {
+		t.Error("the black king controls 3 squares but got", black)
+	}
+}
+
+func TestBestCaptureSEE(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D5)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.E6)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E4)
+	if see := b.BestCaptureSEE(square.D5, piece.White); see > 0 {
+		t.Error("a pawn defended by a pawn can not be won but got", see)
+	}
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.C3)
+	if see := b.BestCaptureSEE(square.D5, piece.White); see != 100 {
+		t.Error("two attackers against one defender win the pawn but got", see)
+	}
+	b.ClearSquare(square.E4)
+	if see := b.BestCaptureSEE(square.D5, piece.White); see != -200 {
+		t.Error("the knight takes a pawn and is taken back, wanted -200 but got", see)
+	}
+	b.ClearSquare(square.E6)
+	if see := b.BestCaptureSEE(square.D5, piece.White); see != 100 {
+		t.Error("the pawn is free, wanted 100 but got", see)
+	}
+	if see := b.BestCaptureSEE(square.D5, piece.Black); see != 0 {
+		t.Error("black can not capture its own pawn but got", see)
+	}
+}
+
+func TestBestCaptureSEEXRay(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.D1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.D2)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.D8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D5)
+	if see := b.BestCaptureSEE(square.D5, piece.White); see != 100 {
+		t.Error("the rook on d1 backs up the one on d2, wanted 100 but got", see)
+	}
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E4)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D5)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.E6)
+	if see := b.BestCaptureSEE(square.D5, piece.White); see != 0 {
+		t.Error("the king can not take a defended pawn but got", see)
+	}
+}
+
+func TestForcingMoves(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.H1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.F5)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.G8)
+	for _, sq := range []square.Square{square.A7, square.F7, square.G7, square.H7} {
+		b.QuickPut(piece.New(piece.Black, piece.Pawn), sq)
+	}
+	contains := func(moves []move.Move, m string) bool {
+		for _, mv := range moves {
+			if mv == move.Parse(m) {
+				return true
+			}
+		}
+		return false
+	}
+	if checks := b.CheckingMoves(); len(checks) != 2 || !contains(checks, "f5e7") || !contains(checks, "f5h6") {
+		t.Error("the knight has two checks but got", checks)
+	}
+	if captures := b.Captures(); len(captures) != 2 || !contains(captures, "a1a7") || !contains(captures, "f5g7") {
+		t.Error("the rook and the knight each have a capture but got", captures)
+	}
+	forcing := b.ForcingMoves()
+	for _, m := range []string{"f5e7", "a1a7", "a1e1"} {
+		if !contains(forcing, m) {
+			t.Error(m, "is forcing but is missing from", forcing)
+		}
+	}
+	if contains(forcing, "h1g2") || contains(forcing, "a1a5") {
+		t.Error("quiet moves should be left out", forcing)
+	}
+}
+
+func TestCaptureTargets(t *testing.T) {
+	b := New()
+	// 1. e4 d5 2. e5 Qd6 3. Nc3 f5
+	for _, m := range []string{"e2e4", "d7d5", "e4e5", "d8d6", "b1c3", "f7f5"} {
+		b.MakeMove(move.Parse(m))
+	}
+	targets := b.CaptureTargets(piece.White)
+	expected := map[square.Square]piece.Piece{
+		square.D6: piece.New(piece.Black, piece.Queen),
+		square.D5: piece.New(piece.Black, piece.Pawn),
+		square.F5: piece.New(piece.Black, piece.Pawn),
+	}
+	if len(targets) != len(expected) {
+		t.Error("wanted", expected, "but got", targets)
+	}
+	for sq, pc := range expected {
+		if targets[sq] != pc {
+			t.Error(sq, "wanted", pc, "but got", targets[sq])
+		}
+	}
+	black := b.CaptureTargets(piece.Black)
+	if len(black) != 1 || black[square.E5] != piece.New(piece.White, piece.Pawn) {
+		t.Error("black can only take on e5 but got", black)
+	}
+}
+
+func TestPromotionMoves(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E7)
+	if promotions := b.PromotionMoves(); len(promotions) != 4 {
+		t.Error("e8 can be any of four pieces but got", promotions)
+	}
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.D8)
+	promotions := b.PromotionMoves()
+	if len(promotions) != 8 {
+		t.Fatal("the pawn can also take on d8 but got", promotions)
+	}
+	if promotions[0] != move.Parse("e7d8b") {
+		t.Error("promotions should be sorted but got", promotions)
+	}
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.E8)
+	if promotions := b.PromotionMoves(); len(promotions) != 4 {
+		t.Error("e8 is blocked but got", promotions)
+	}
+}
+
+func TestExchangeSequence(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.A1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.D1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.D2)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.H8)
+	b.QuickPut(piece.New(piece.Black, piece.Rook), square.D8)
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.D5)
+	expected := []piece.Piece{
+		piece.New(piece.Black, piece.Pawn),
+		piece.New(piece.White, piece.Rook),
+		piece.New(piece.Black, piece.Rook),
+	}
+	sequence := b.ExchangeSequence(square.D5)
+	if len(sequence) != len(expected) {
+		t.Fatal("wanted", expected, "but got", sequence)
+	}
+	for i := range expected {
+		if sequence[i] != expected[i] {
+			t.Error("capture", i, "wanted", expected[i], "but got", sequence[i])
+		}
+	}
+	// once the e6 pawn defends it too, taking it loses a rook:
+	b.QuickPut(piece.New(piece.Black, piece.Pawn), square.E6)
+	if sequence := b.ExchangeSequence(square.D5); len(sequence) != 0 {
+		t.Error("the pawn is defended too well to take but got", sequence)
+	}
+	if sequence := b.ExchangeSequence(square.H8); sequence != nil {
+		t.Error("nothing can take the king", sequence)
+	}
+}
+
+func TestStalemateTraps(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.C7)
+	b.QuickPut(piece.New(piece.White, piece.Queen), square.D1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.A8)
+	traps := b.StalemateTraps(piece.White)
+	// the queen takes a7 away from the king on d4 and g1, while on the a-file
+	// it would give check instead:
+	expected := map[square.Square]bool{square.D4: true, square.G1: true}
+	if len(traps) != len(expected) {
+		t.Fatal("wanted", expected, "but got", traps)
+	}
+	for _, sq := range traps {
+		if !expected[sq] {
+			t.Error(sq, "does not stalemate the king")
+		}
+	}
+	if traps := b.StalemateTraps(piece.Black); traps != nil {
+		t.Error("black can not stalemate white but got", traps)
+	}
+}
+
+func TestDefensiveMoves(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.H1)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.E5)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.G1)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.C1)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.E1)
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.E3)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.A8)
+	b.SetSideToMove(piece.Black)
+	moves := b.DefensiveMoves(square.E5, piece.White)
+	found := make(map[move.Move]bool)
+	for _, m := range moves {
+		found[m] = true
+	}
+	// the knight on e3 moving away lets the rook defend e5 from behind:
+	for _, m := range []string{"g1f3", "c1b2", "e3c4", "e3d1"} {
+		if !found[move.Parse(m)] {
+			t.Error(m, "defends e5 but is missing from", moves)
+		}
+	}
+	for _, m := range []string{"h1g2", "e5e6", "c1d2", "e1d1"} {
+		if found[move.Parse(m)] {
+			t.Error(m, "does not add a defender to e5")
+		}
+	}
+}
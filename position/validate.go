@@ -0,0 +1,44 @@
+package position
+
+import (
+	"errors"
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+)
+
+// IsLegal returns an error describing why the position could not have come up
+// in a game, or nil if it could. A legal position has exactly one king of each
+// color, no pawns on the first or last rank and the side that is not to move
+// is not in check. Since a single move can only give check with two pieces at
+// once (one of them by discovery), the side to move can not be in check by
+// more than two pieces and, in a double check, one of the checkers has to be
+// a bishop, rook or queen.
+func (p *Position) IsLegal() error {
+	colors := []string{"white", "black"}
+	for c := piece.White; c <= piece.Black; c++ {
+		if kings := popcount(p.bitBoard[c][piece.King]); kings != 1 {
+			return errors.New(colors[c] + " needs exactly one king")
+		}
+		// ranks 1 and 8:
+		if p.bitBoard[c][piece.Pawn]&0xFF000000000000FF != 0 {
+			return errors.New(colors[c] + " has a pawn on the first or last rank")
+		}
+	}
+	notToMove := []piece.Color{piece.Black, piece.White}[p.ActiveColor]
+	if p.Check(notToMove) {
+		return errors.New(colors[notToMove] + " is in check but it is not their move")
+	}
+	king := square.Square(bitscan(p.bitBoard[p.ActiveColor][piece.King]))
+	checkers := p.attackers(king, notToMove)
+	switch popcount(checkers) {
+	case 0, 1:
+	case 2:
+		sliders := p.bitBoard[notToMove][piece.Bishop] | p.bitBoard[notToMove][piece.Rook] | p.bitBoard[notToMove][piece.Queen]
+		if checkers&sliders == 0 {
+			return errors.New(colors[p.ActiveColor] + " is in a double check that no move could have given")
+		}
+	default:
+		return errors.New(colors[p.ActiveColor] + " is in check by more than two pieces")
+	}
+	return nil
+}
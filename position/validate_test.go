@@ -0,0 +1,57 @@
+package position
+
+import (
+	"github.com/reecer/chess/piece"
+	"github.com/reecer/chess/position/square"
+	"testing"
+)
+
+func TestIsLegal(t *testing.T) {
+	if err := New().IsLegal(); err != nil {
+		t.Error("the start position is legal but got", err)
+	}
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	if b.IsLegal() == nil {
+		t.Error("black has no king")
+	}
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.A8)
+	if b.IsLegal() == nil {
+		t.Error("there is a pawn on a8")
+	}
+	b.ClearSquare(square.A8)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.E4)
+	b.ActiveColor = piece.White
+	if b.IsLegal() == nil {
+		t.Error("black is in check on white's move")
+	}
+	b.ActiveColor = piece.Black
+	if err := b.IsLegal(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsLegalCheckers(t *testing.T) {
+	b := New()
+	b.Clear()
+	b.QuickPut(piece.New(piece.White, piece.King), square.E1)
+	b.QuickPut(piece.New(piece.Black, piece.King), square.E8)
+	b.QuickPut(piece.New(piece.White, piece.Rook), square.E4)
+	b.QuickPut(piece.New(piece.White, piece.Bishop), square.B5)
+	b.ActiveColor = piece.Black
+	if err := b.IsLegal(); err != nil {
+		t.Error("a double check by a rook and a bishop can happen:", err)
+	}
+	b.QuickPut(piece.New(piece.White, piece.Knight), square.D6)
+	if b.IsLegal() == nil {
+		t.Error("black is in triple check")
+	}
+	b.ClearSquare(square.E4)
+	b.ClearSquare(square.B5)
+	b.QuickPut(piece.New(piece.White, piece.Pawn), square.F7)
+	if b.IsLegal() == nil {
+		t.Error("a knight and a pawn can not give check at the same time")
+	}
+}